@@ -0,0 +1,23 @@
+package appengine
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// onGoogleCloud reports whether the program is running on Google Cloud.
+// Replaced in tests.
+var onGoogleCloud = func() bool {
+	return DetectResource(AppEngineDetector, CloudFunctionsDetector, CloudRunDetector, GKEDetector, GCEDetector) != nil
+}
+
+// AutoFormatter returns a Formatter if the program is running on Google
+// Cloud, as detected from the environment variables and the metadata server,
+// and logrus.TextFormatter otherwise, so developers don't have to read raw
+// JSON during local runs. Detection may take a while outside of Google
+// Cloud, see GCEDetector.
+func AutoFormatter() log.Formatter {
+	if onGoogleCloud() {
+		return &Formatter{}
+	}
+	return &log.TextFormatter{FullTimestamp: true}
+}
@@ -0,0 +1,31 @@
+package appengine
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestAutoFormatter(t *testing.T) {
+	defer func(f func() bool) { onGoogleCloud = f }(onGoogleCloud)
+
+	onGoogleCloud = func() bool { return true }
+	if f := AutoFormatter(); !isFormatter(f) {
+		t.Errorf("AutoFormatter() = %T on Google Cloud, want *Formatter", f)
+	}
+
+	onGoogleCloud = func() bool { return false }
+	if f := AutoFormatter(); !isTextFormatter(f) {
+		t.Errorf("AutoFormatter() = %T locally, want *logrus.TextFormatter", f)
+	}
+}
+
+func isFormatter(f log.Formatter) bool {
+	_, ok := f.(*Formatter)
+	return ok
+}
+
+func isTextFormatter(f log.Formatter) bool {
+	_, ok := f.(*log.TextFormatter)
+	return ok
+}
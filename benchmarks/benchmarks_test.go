@@ -0,0 +1,77 @@
+package benchmarks
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var soakDuration = flag.Duration("soak", 0, "run soak tests for the given duration per configuration")
+
+func BenchmarkFormat(b *testing.B) {
+	for _, cfg := range Configs() {
+		for _, corpus := range Corpora(100) {
+			b.Run(cfg.Name+"/"+corpus.Name, func(b *testing.B) {
+				logger := log.New()
+				logger.SetReportCaller(cfg.ReportCaller)
+				for _, e := range corpus.Entries {
+					e.Logger = logger
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := cfg.Formatter.Format(corpus.Entries[i%len(corpus.Entries)]); err != nil {
+						b.Fatal("Unable to format entry: ", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSoak(t *testing.T) {
+	d := *soakDuration
+	if d == 0 {
+		// Just make sure the runner works.
+		d = time.Millisecond
+	}
+	for _, cfg := range Configs() {
+		for _, corpus := range Corpora(100) {
+			res, err := Soak(cfg, corpus, d)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", cfg.Name, corpus.Name, err)
+			}
+			if res.Entries == 0 || res.Bytes == 0 {
+				t.Errorf("%s/%s: nothing was formatted: %+v", cfg.Name, corpus.Name, res)
+			}
+			t.Log(res)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := &histogram{}
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Microsecond)
+	}
+	for _, test := range []struct {
+		p    int
+		want time.Duration
+	}{
+		{50, 500 * time.Microsecond},
+		{99, 990 * time.Microsecond},
+	} {
+		got := h.percentile(test.p)
+		if got < test.want || got > test.want+test.want/16 {
+			t.Errorf("percentile(%d) = %v, want within 1/16 above %v", test.p, got, test.want)
+		}
+	}
+	for v := uint64(0); v < 1<<20; v += 7 {
+		i := histogramBucket(v)
+		if v < histogramBucketMin(i) || v >= histogramBucketMin(i+1) {
+			t.Fatalf("histogramBucket(%d) = %d, which covers [%d, %d)", v, i, histogramBucketMin(i), histogramBucketMin(i+1))
+		}
+	}
+}
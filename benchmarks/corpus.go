@@ -0,0 +1,95 @@
+// Package benchmarks contains realistic entry corpora and a soak runner for
+// measuring performance of the appengine formatter under different
+// configurations.
+package benchmarks
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	log "github.com/sirupsen/logrus"
+)
+
+// Corpus is a named set of entries to format.
+type Corpus struct {
+	Name    string
+	Entries []*log.Entry
+}
+
+var paths = []string{"/", "/api/v1/users", "/api/v1/orders", "/healthz", "/static/app.js"}
+
+// AccessLogs returns a corpus of n request log entries, similar to what an
+// HTTP server produces for every request.
+func AccessLogs(n int) Corpus {
+	logger := log.New()
+	entries := make([]*log.Entry, n)
+	for i := range entries {
+		e := logger.WithFields(log.Fields{
+			"trace":   fmt.Sprintf("%032x", i),
+			"sampled": i%10 == 0,
+			"req": &appengine.HTTPRequest{
+				Method:       "GET",
+				URL:          paths[i%len(paths)],
+				Status:       200,
+				ResponseSize: int64(100 + i%1000),
+				UserAgent:    "Mozilla/5.0 (X11; Linux x86_64)",
+				RemoteIP:     "192.0.2.1",
+				Latency:      time.Duration(i%500) * time.Millisecond,
+			},
+		})
+		e.Level = log.InfoLevel
+		e.Message = "request served"
+		e.Time = time.Unix(1500000000, int64(i))
+		entries[i] = e
+	}
+	return Corpus{Name: "access", Entries: entries}
+}
+
+// ErrorLogs returns a corpus of n error entries with wrapped errors and a
+// handful of fields.
+func ErrorLogs(n int) Corpus {
+	logger := log.New()
+	base := errors.New("connection reset by peer")
+	entries := make([]*log.Entry, n)
+	for i := range entries {
+		e := logger.WithError(fmt.Errorf("query %d: %w", i, base)).WithFields(log.Fields{
+			"trace":   fmt.Sprintf("%032x", i),
+			"user_id": i,
+			"attempt": i % 3,
+			"table":   "orders",
+		})
+		e.Level = log.ErrorLevel
+		e.Message = "failed to load orders"
+		e.Time = time.Unix(1500000000, int64(i))
+		e.Caller = &runtime.Frame{
+			Function: "example.com/app/storage.(*DB).LoadOrders",
+			File:     "/src/example.com/app/storage/db.go",
+			Line:     100 + i%50,
+		}
+		entries[i] = e
+	}
+	return Corpus{Name: "errors", Entries: entries}
+}
+
+// Mixed returns a corpus of n entries, mostly access logs interleaved with
+// occasional errors.
+func Mixed(n int) Corpus {
+	access := AccessLogs(n)
+	errs := ErrorLogs(n / 10)
+	entries := make([]*log.Entry, 0, n)
+	for i, e := range access.Entries {
+		if i%10 == 9 && i/10 < len(errs.Entries) {
+			e = errs.Entries[i/10]
+		}
+		entries = append(entries, e)
+	}
+	return Corpus{Name: "mixed", Entries: entries}
+}
+
+// Corpora returns all built-in corpora with n entries each.
+func Corpora(n int) []Corpus {
+	return []Corpus{AccessLogs(n), ErrorLogs(n), Mixed(n)}
+}
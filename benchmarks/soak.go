@@ -0,0 +1,181 @@
+package benchmarks
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"runtime"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config is a named formatter configuration to measure.
+type Config struct {
+	Name      string
+	Formatter *appengine.Formatter
+	// ReportCaller enables caller reporting on the entries being formatted.
+	ReportCaller bool
+}
+
+// Configs returns a set of configurations covering the most expensive
+// formatter features, including redaction and trace sampling fields.
+func Configs() []Config {
+	return []Config{
+		{Name: "default", Formatter: &appengine.Formatter{}},
+		{Name: "caller", Formatter: &appengine.Formatter{TrimFilenamePrefix: "/src/"}, ReportCaller: true},
+		{Name: "trace", Formatter: &appengine.Formatter{TraceKey: "trace", ProjectID: "my-project", TraceURL: true}},
+		{Name: "labels", Formatter: &appengine.Formatter{Labels: map[string]string{"service": "api", "ring": "prod"}}},
+		{Name: "ordered", Formatter: &appengine.Formatter{FieldOrder: appengine.HighSignalFieldOrder}},
+		{Name: "pretty", Formatter: &appengine.Formatter{PrettyPrint: true}},
+		{Name: "redaction", Formatter: &appengine.Formatter{RedactKeys: []string{"user_id", "password", "authorization"}}},
+		{Name: "sampling", Formatter: &appengine.Formatter{TraceKey: "trace", TraceSampledKey: "sampled", ProjectID: "my-project"}},
+		{Name: "interned", Formatter: &appengine.Formatter{Interner: appengine.NewInterner(append(paths, "request served", "message", "severity", "level", "trace")...)}},
+		{
+			Name: "everything",
+			Formatter: &appengine.Formatter{
+				TraceKey:         "trace",
+				ProjectID:        "my-project",
+				TraceURL:         true,
+				Labels:           map[string]string{"service": "api"},
+				FieldOrder:       appengine.HighSignalFieldOrder,
+				GenerateInsertID: true,
+				ErrorReporting:   true,
+				ErrorKind:        appengine.ClassifyError,
+			},
+			ReportCaller: true,
+		},
+	}
+}
+
+// Result holds measurements of a single soak run.
+type Result struct {
+	Config  string
+	Corpus  string
+	Entries int
+	Elapsed time.Duration
+	Bytes   int64
+	// AllocsPerEntry and AllocBytesPerEntry are averages over the run.
+	AllocsPerEntry     float64
+	AllocBytesPerEntry float64
+	// P50 and P99 are percentiles of a single Format call latency, rounded
+	// up to the histogram bucket bound.
+	P50 time.Duration
+	P99 time.Duration
+}
+
+// Throughput returns the number of entries formatted per second.
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Entries) / r.Elapsed.Seconds()
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%s/%s: %d entries, %.0f entries/s, %.1f allocs/entry, %.0f B/entry, p50 %v, p99 %v",
+		r.Config, r.Corpus, r.Entries, r.Throughput(), r.AllocsPerEntry, r.AllocBytesPerEntry, r.P50, r.P99)
+}
+
+// Soak formats entries from the corpus in a loop for the given duration and
+// returns the measurements. It returns an error if any of the entries fails
+// to format.
+func Soak(cfg Config, corpus Corpus, d time.Duration) (Result, error) {
+	res := Result{Config: cfg.Name, Corpus: corpus.Name}
+	if len(corpus.Entries) == 0 {
+		return res, nil
+	}
+	logger := log.New()
+	logger.SetReportCaller(cfg.ReportCaller)
+	entries := make([]*log.Entry, len(corpus.Entries))
+	for i, e := range corpus.Entries {
+		e = appengine.CloneEntry(e)
+		e.Logger = logger
+		entries[i] = e
+	}
+
+	latencies := &histogram{}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for time.Since(start) < d {
+		for _, e := range entries {
+			t := time.Now()
+			b, err := cfg.Formatter.Format(e)
+			latencies.record(time.Since(t))
+			if err != nil {
+				return res, err
+			}
+			res.Bytes += int64(len(b))
+		}
+	}
+	res.Elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	res.Entries = int(latencies.total)
+	res.AllocsPerEntry = float64(after.Mallocs-before.Mallocs) / float64(res.Entries)
+	res.AllocBytesPerEntry = float64(after.TotalAlloc-before.TotalAlloc) / float64(res.Entries)
+	res.P50 = latencies.percentile(50)
+	res.P99 = latencies.percentile(99)
+	return res, nil
+}
+
+// histogramSubBits is the number of bits of a value used to pick a bucket
+// within a power of two.
+const histogramSubBits = 4
+
+// histogram is a fixed-size latency histogram, so that recording a latency
+// neither allocates nor grows with the duration of the run. Every power of
+// two is split into 1<<histogramSubBits buckets, which keeps percentiles
+// within about 6% of the exact values.
+type histogram struct {
+	counts [64 << histogramSubBits]uint64
+	total  uint64
+}
+
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[histogramBucket(uint64(d))]++
+	h.total++
+}
+
+// percentile returns the upper bound of the bucket holding the p-th
+// percentile of recorded latencies.
+func (h *histogram) percentile(p int) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := h.total * uint64(p) / 100
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen > rank {
+			return time.Duration(histogramBucketMin(i+1) - 1)
+		}
+	}
+	return time.Duration(math.MaxInt64)
+}
+
+// histogramBucket returns the index of the bucket holding v.
+func histogramBucket(v uint64) int {
+	if v < 1<<histogramSubBits {
+		return int(v)
+	}
+	shift := bits.Len64(v) - histogramSubBits - 1
+	sub := (v >> uint(shift)) & (1<<histogramSubBits - 1)
+	return (shift+1)<<histogramSubBits + int(sub)
+}
+
+// histogramBucketMin returns the smallest value in the i-th bucket.
+func histogramBucketMin(i int) uint64 {
+	if i < 1<<histogramSubBits {
+		return uint64(i)
+	}
+	shift := uint(i>>histogramSubBits - 1)
+	sub := uint64(i & (1<<histogramSubBits - 1))
+	return (1<<histogramSubBits + sub) << shift
+}
@@ -0,0 +1,151 @@
+package appengine
+
+import (
+	"encoding/base64"
+	"mime"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultBodyMaxSize is the default value of BodyOptions.MaxSize.
+const DefaultBodyMaxSize = 4096
+
+// DefaultBodyRedactKeys is the default value of BodyOptions.RedactKeys.
+var DefaultBodyRedactKeys = []string{"password", "passwd", "secret", "token", "access_token", "refresh_token", "api_key", "apikey", "authorization"}
+
+const redacted = "[REDACTED]"
+
+// BodyOptions controls how LogBody renders bodies. Zero value is usable.
+type BodyOptions struct {
+	// MaxSize caps the number of bytes of the body that are logged.
+	// DefaultBodyMaxSize is used if zero.
+	MaxSize int
+
+	// ContentTypes lists media types logged as text. Types ending with "/*"
+	// match all subtypes. JSON and text types are allowed by default.
+	ContentTypes []string
+
+	// Binary enables logging bodies of other content types base64-encoded.
+	// Otherwise they are omitted.
+	Binary bool
+
+	// RedactKeys lists names of JSON object keys and form or query
+	// parameters, matched case-insensitively, values of which are replaced
+	// with "[REDACTED]". DefaultBodyRedactKeys is used if nil.
+	RedactKeys []string
+}
+
+// Body is a request or response body prepared for logging, see LogBody.
+type Body struct {
+	ContentType string `json:"contentType,omitempty"`
+	// Size is the full size of the body, which may be larger than the
+	// logged part.
+	Size      int    `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Omitted   bool   `json:"omitted,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Base64    string `json:"base64,omitempty"`
+}
+
+var defaultBodyContentTypes = []string{"application/json", "application/*+json", "application/x-www-form-urlencoded", "text/*"}
+
+// LogBody returns body of the given content type prepared to be attached to
+// an entry: capped in size, with secrets redacted, and base64-encoded or
+// omitted if it's not text. opts may be nil.
+func LogBody(contentType string, body []byte, opts *BodyOptions) *Body {
+	if opts == nil {
+		opts = &BodyOptions{}
+	}
+	b := &Body{ContentType: contentType, Size: len(body)}
+	max := opts.MaxSize
+	if max <= 0 {
+		max = DefaultBodyMaxSize
+	}
+	if len(body) > max {
+		// Don't cut multi-byte characters of text bodies in half.
+		cut := max
+		for cut > max-utf8.UTFMax && cut > 0 && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+		if cut == max-utf8.UTFMax || cut == 0 {
+			cut = max
+		}
+		body = body[:cut]
+		b.Truncated = true
+	}
+
+	types := opts.ContentTypes
+	if types == nil {
+		types = defaultBodyContentTypes
+	}
+	if !matchContentType(contentType, types) || !utf8.Valid(body) {
+		if opts.Binary {
+			b.Base64 = base64.StdEncoding.EncodeToString(body)
+		} else {
+			b.Omitted = true
+		}
+		return b
+	}
+
+	keys := opts.RedactKeys
+	if keys == nil {
+		keys = DefaultBodyRedactKeys
+	}
+	b.Text = redactBody(string(body), keys)
+	return b
+}
+
+// matchContentType reports whether media type of contentType is in types.
+func matchContentType(contentType string, types []string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range types {
+		switch {
+		case allowed == t:
+			return true
+		case strings.HasSuffix(allowed, "/*") && strings.HasPrefix(t, allowed[:len(allowed)-1]):
+			return true
+		case strings.HasPrefix(allowed, "application/*+") && strings.HasPrefix(t, "application/") &&
+			strings.HasSuffix(t, allowed[len("application/*"):]):
+			return true
+		}
+	}
+	return false
+}
+
+// redactBody replaces values of the given keys in JSON objects and
+// key=value pairs of s. It works on text rather than parsed values, so that
+// truncated bodies are redacted too.
+func redactBody(s string, keys []string) string {
+	if len(keys) == 0 {
+		return s
+	}
+	re := redactRegexps(keys)
+	s = re[0].ReplaceAllString(s, `${1}"`+redacted+`"`)
+	return re[1].ReplaceAllString(s, "${1}${2}"+redacted)
+}
+
+// redactCache maps lists of keys joined with "|" to regexps matching their
+// values in JSON and in key=value pairs.
+var redactCache sync.Map
+
+func redactRegexps(keys []string) [2]*regexp.Regexp {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	names := strings.Join(quoted, "|")
+	if re, ok := redactCache.Load(names); ok {
+		return re.([2]*regexp.Regexp)
+	}
+	re := [2]*regexp.Regexp{
+		regexp.MustCompile(`(?i)("(?:` + names + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`),
+		regexp.MustCompile(`(?i)(^|[&?\s;])((?:` + names + `)=)[^&\s;]*`),
+	}
+	redactCache.Store(names, re)
+	return re
+}
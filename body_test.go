@@ -0,0 +1,90 @@
+package appengine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLogBody(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		contentType string
+		body        string
+		opts        *BodyOptions
+		want        Body
+	}{
+		{
+			desc:        "JSON",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"user":"walrus","Password":"hunter2","token":42,"nested":{"api_key":"k\"ey"}}`,
+			want:        Body{Text: `{"user":"walrus","Password":"[REDACTED]","token":"[REDACTED]","nested":{"api_key":"[REDACTED]"}}`},
+		},
+		{
+			desc:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user=walrus&password=hunter2&next=/",
+			want:        Body{Text: "user=walrus&password=[REDACTED]&next=/"},
+		},
+		{
+			desc:        "vendor JSON",
+			contentType: "application/vnd.api+json",
+			body:        `{"secret":"x"}`,
+			want:        Body{Text: `{"secret":"[REDACTED]"}`},
+		},
+		{
+			desc:        "truncated",
+			contentType: "text/plain",
+			body:        "token=abcdef",
+			opts:        &BodyOptions{MaxSize: 9},
+			want:        Body{Truncated: true, Text: "token=[REDACTED]"},
+		},
+		{
+			desc:        "truncated multi-byte character",
+			contentType: "text/plain",
+			body:        "ab€",
+			opts:        &BodyOptions{MaxSize: 4},
+			want:        Body{Truncated: true, Text: "ab"},
+		},
+		{
+			desc:        "binary omitted",
+			contentType: "image/png",
+			body:        "\x89PNG",
+			want:        Body{Omitted: true},
+		},
+		{
+			desc:        "binary",
+			contentType: "image/png",
+			body:        "\x89PNG",
+			opts:        &BodyOptions{Binary: true},
+			want:        Body{Base64: "iVBORw=="},
+		},
+		{
+			desc:        "invalid UTF-8 text",
+			contentType: "text/plain",
+			body:        "\xff\xfe",
+			want:        Body{Omitted: true},
+		},
+		{
+			desc:        "custom content types and keys",
+			contentType: "application/xml",
+			body:        "<pin>1234</pin>",
+			opts:        &BodyOptions{ContentTypes: []string{"application/xml"}, RedactKeys: []string{}},
+			want:        Body{Text: "<pin>1234</pin>"},
+		},
+	} {
+		test.want.ContentType = test.contentType
+		test.want.Size = len(test.body)
+		got := LogBody(test.contentType, []byte(test.body), test.opts)
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.desc, *got, test.want)
+		}
+	}
+}
+
+func TestLogBodyDefaultMaxSize(t *testing.T) {
+	got := LogBody("text/plain", []byte(strings.Repeat("a", DefaultBodyMaxSize+1)), nil)
+	if !got.Truncated || len(got.Text) != DefaultBodyMaxSize {
+		t.Errorf("Got truncated %v and %d bytes of text, want %d", got.Truncated, len(got.Text), DefaultBodyMaxSize)
+	}
+}
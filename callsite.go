@@ -0,0 +1,104 @@
+package appengine
+
+import (
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const sourceLocationKey = "logging.googleapis.com/sourceLocation"
+
+// callSite is the stack recorded by WithError.
+type callSite []uintptr
+
+// WithError is like logrus.WithError, but also records where it was called
+// from. The recorded location is used as the source location (and report
+// location, unless the error carries its own stack trace) of the entry, so
+// they point at the place the error was observed rather than the place the
+// entry was eventually logged at.
+func WithError(err error) *log.Entry {
+	return withErrorAt(log.NewEntry(log.StandardLogger()), err, 3)
+}
+
+// EntryWithError is like WithError, but adds the error to an existing entry.
+func EntryWithError(entry *log.Entry, err error) *log.Entry {
+	return withErrorAt(entry, err, 3)
+}
+
+func withErrorAt(entry *log.Entry, err error, skip int) *log.Entry {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip, pcs)
+	return entry.WithFields(log.Fields{
+		log.ErrorKey:      err,
+		sourceLocationKey: callSite(pcs[:n]),
+	})
+}
+
+func isCallSite(v interface{}) bool {
+	_, ok := v.(callSite)
+	return ok
+}
+
+// entryCallSite returns the stack recorded by WithError, or nil.
+func entryCallSite(entry *log.Entry) []runtime.Frame {
+	if v, ok := findTypedField(entry, sourceLocationKey, isCallSite); ok {
+		return framesFromPCs(v.(callSite))
+	}
+	return nil
+}
+
+// entryCaller returns the source location of the entry: the one recorded by
+// WithError, or the entry caller if it's reported, adjusted according to
+// CallerSkip and CallerSkipPackages. Returns nil if neither is available.
+func (f *Formatter) entryCaller(entry *log.Entry) *runtime.Frame {
+	if frames := entryCallSite(entry); len(frames) > 0 {
+		return &frames[0]
+	}
+	if entry.HasCaller() {
+		return f.skipCallerFrames(entry.Caller)
+	}
+	return nil
+}
+
+// skipCallerFrames returns the frame CallerSkip frames up the stack from
+// caller, not counting frames of CallerSkipPackages. The caller is looked up
+// in the current stack, so if the entry is formatted elsewhere (e.g. in
+// another goroutine), the caller is returned as is.
+func (f *Formatter) skipCallerFrames(caller *runtime.Frame) *runtime.Frame {
+	if f.CallerSkip <= 0 && len(f.CallerSkipPackages) == 0 {
+		return caller
+	}
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := framesFromPCs(pcs[:n])
+	i := 0
+	for i < len(frames) && !sameLocation(&frames[i], caller) {
+		i++
+	}
+	skip := f.CallerSkip
+	for ; i < len(frames); i++ {
+		if f.isSkippedPackage(funcPackage(frames[i].Function)) {
+			continue
+		}
+		if skip == 0 {
+			return &frames[i]
+		}
+		skip--
+	}
+	return caller
+}
+
+// isSkippedPackage reports whether pkg matches one of CallerSkipPackages.
+func (f *Formatter) isSkippedPackage(pkg string) bool {
+	for _, p := range f.CallerSkipPackages {
+		if pkg == p || strings.HasSuffix(p, "/...") && strings.HasPrefix(pkg+"/", strings.TrimSuffix(p, "...")) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameLocation(a, b *runtime.Frame) bool {
+	return a.Function == b.Function && a.File == b.File && a.Line == b.Line
+}
@@ -0,0 +1,132 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func errorObservedHere(err error) *log.Entry {
+	return WithError(err)
+}
+
+func TestWithErrorCallSite(t *testing.T) {
+	formatter := &Formatter{StackTrace: true, ErrorReporting: true}
+
+	e := errorObservedHere(errors.New("wild walrus"))
+	e.Level = log.ErrorLevel
+	e.Caller = &runtime.Frame{Function: "main.elsewhere", File: "/src/main.go", Line: 10}
+	entry := formatToMap(t, formatter, e)
+
+	l, _ := entry[sourceLocationKey].(map[string]interface{})
+	if name, _ := l["function"].(string); !strings.HasSuffix(name, ".errorObservedHere") {
+		t.Errorf("%s = %v, want location of WithError call", sourceLocationKey, entry[sourceLocationKey])
+	}
+	l, _ = entry["context"].(map[string]interface{})["reportLocation"].(map[string]interface{})
+	if name, _ := l["functionName"].(string); !strings.HasSuffix(name, ".errorObservedHere") {
+		t.Errorf("reportLocation = %v, want location of WithError call", l)
+	}
+	stack, _ := entry["stack_trace"].(string)
+	if !strings.Contains(stack, ".errorObservedHere(...)") || !strings.Contains(stack, ".TestWithErrorCallSite(...)") {
+		t.Errorf("stack_trace doesn't start at WithError call: %q", stack)
+	}
+	if entry[log.ErrorKey] != "wild walrus" {
+		t.Errorf("%s = %v, want wild walrus", log.ErrorKey, entry[log.ErrorKey])
+	}
+}
+
+func TestEntryWithError(t *testing.T) {
+	formatter := &Formatter{}
+
+	e := EntryWithError(log.WithField("a", 1), errors.New("wild walrus"))
+	entry := formatToMap(t, formatter, e)
+
+	l, _ := entry[sourceLocationKey].(map[string]interface{})
+	if name, _ := l["function"].(string); !strings.HasSuffix(name, ".TestEntryWithError") {
+		t.Errorf("%s = %v, want location of EntryWithError call", sourceLocationKey, entry[sourceLocationKey])
+	}
+	if entry["a"] != float64(1) {
+		t.Errorf("Existing fields lost: %v", entry)
+	}
+}
+
+// logViaHelper uses Entry.Log, since logrus v1.4.1 reports a wrong caller
+// for the level methods.
+//
+//go:noinline
+func logViaHelper(logger *log.Logger) {
+	log.NewEntry(logger).Log(log.InfoLevel, "from helper")
+}
+
+func TestCallerSkip(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	logger.ReportCaller = true
+
+	for _, test := range []struct {
+		desc string
+		f    *Formatter
+		want string
+	}{
+		{"no skip", &Formatter{}, ".logViaHelper"},
+		{"skip helper", &Formatter{CallerSkip: 1}, ".TestCallerSkip"},
+	} {
+		b.Reset()
+		logger.Formatter = test.f
+		logViaHelper(logger)
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+			t.Fatal("Unable to unmarshal formatted entry: ", err)
+		}
+		l, _ := entry[sourceLocationKey].(map[string]interface{})
+		if name, _ := l["function"].(string); !strings.HasSuffix(name, test.want) {
+			t.Errorf("%s: function = %v, want %s", test.desc, l["function"], test.want)
+		}
+	}
+}
+
+func TestIsSkippedPackage(t *testing.T) {
+	f := &Formatter{CallerSkipPackages: []string{"example.com/log", "example.com/wrappers/..."}}
+	for _, test := range []struct {
+		pkg  string
+		want bool
+	}{
+		{"example.com/log", true},
+		{"example.com/log/sub", false},
+		{"example.com/logger", false},
+		{"example.com/wrappers", true},
+		{"example.com/wrappers/zap", true},
+		{"example.com/wrappersx", false},
+	} {
+		if got := f.isSkippedPackage(test.pkg); got != test.want {
+			t.Errorf("isSkippedPackage(%q) = %v, want %v", test.pkg, got, test.want)
+		}
+	}
+}
+
+func TestSourceLineAsString(t *testing.T) {
+	log.SetReportCaller(true)
+	defer log.SetReportCaller(false)
+
+	e := log.WithField("a", 1)
+	e.Caller = &runtime.Frame{Function: "main.handler", File: "/src/main.go", Line: 10}
+	for _, test := range []struct {
+		f    *Formatter
+		want interface{}
+	}{
+		{&Formatter{}, float64(10)},
+		{&Formatter{SourceLineAsString: true}, "10"},
+	} {
+		entry := formatToMap(t, test.f, e)
+		l, _ := entry[sourceLocationKey].(map[string]interface{})
+		if l["line"] != test.want {
+			t.Errorf("line = %#v with SourceLineAsString = %v, want %#v", l["line"], test.f.SourceLineAsString, test.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package appengine
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// KeyCasing selects how Formatter transforms output keys.
+type KeyCasing int
+
+// Supported key casings.
+const (
+	// KeyCasingNone leaves keys as they are.
+	KeyCasingNone KeyCasing = iota
+	// KeyCasingSnake converts keys to snake_case, e.g. "requestId" to
+	// "request_id".
+	KeyCasingSnake
+	// KeyCasingCamel converts keys to camelCase, e.g. "request_id" to
+	// "requestId".
+	KeyCasingCamel
+)
+
+// fixedCaseKeys are the keys Cloud Logging and Error Reporting recognize
+// that are not single words, so changing their case would break parsing.
+var fixedCaseKeys = map[string]bool{
+	httpRequestKey:           true,
+	"serviceContext":         true,
+	"stack_trace":            true,
+	FieldKeyTimestampSeconds: true,
+	FieldKeyTimestampNanos:   true,
+}
+
+// applyKeyCasing renames top-level keys of data according to c. Keys
+// reserved by Cloud Logging are left intact. If a renamed key clashes with
+// an existing one, the existing one is kept and the key isn't renamed.
+func applyKeyCasing(data log.Fields, c KeyCasing) {
+	var convert func(string) string
+	switch c {
+	case KeyCasingSnake:
+		convert = snakeCase
+	case KeyCasingCamel:
+		convert = camelCase
+	default:
+		return
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if fixedCaseKeys[k] || strings.HasPrefix(k, "logging.googleapis.com/") {
+			continue
+		}
+		nk := convert(k)
+		if nk == k {
+			continue
+		}
+		if _, set := data[nk]; set {
+			continue
+		}
+		data[nk] = data[k]
+		delete(data, k)
+	}
+}
+
+// snakeCase converts s to snake_case. Runs of upper case letters are
+// treated as a single word, so "HTTPStatus" becomes "http_status".
+func snakeCase(s string) string {
+	r := []rune(s)
+	b := &strings.Builder{}
+	for i, c := range r {
+		switch {
+		case c == '-' || c == ' ':
+			b.WriteByte('_')
+		case unicode.IsUpper(c):
+			if i > 0 && r[i-1] != '_' && r[i-1] != '-' && r[i-1] != ' ' && r[i-1] != '.' &&
+				(!unicode.IsUpper(r[i-1]) || i+1 < len(r) && unicode.IsLower(r[i+1])) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(c))
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// camelCase converts s to camelCase, removing '_', '-' and ' ' separators.
+func camelCase(s string) string {
+	b := &strings.Builder{}
+	upper := false
+	for _, c := range s {
+		switch {
+		case c == '_' || c == '-' || c == ' ':
+			upper = b.Len() > 0
+		case upper:
+			b.WriteRune(unicode.ToUpper(c))
+			upper = false
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
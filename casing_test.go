@@ -0,0 +1,67 @@
+package appengine
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestKeyCase(t *testing.T) {
+	for _, test := range []struct {
+		in, snake, camel string
+	}{
+		{"requestId", "request_id", "requestId"},
+		{"request_id", "request_id", "requestId"},
+		{"request-id", "request_id", "requestId"},
+		{"HTTPStatus", "http_status", "HTTPStatus"},
+		{"userID", "user_id", "userID"},
+		{"fields.level", "fields.level", "fields.level"},
+		{"_private", "_private", "private"},
+	} {
+		if got := snakeCase(test.in); got != test.snake {
+			t.Errorf("snakeCase(%q) = %q, want %q", test.in, got, test.snake)
+		}
+		if got := camelCase(test.in); got != test.camel {
+			t.Errorf("camelCase(%q) = %q, want %q", test.in, got, test.camel)
+		}
+	}
+}
+
+func TestKeyCasing(t *testing.T) {
+	formatter := &Formatter{
+		KeyCasing: KeyCasingSnake,
+		TraceKey:  "traceId",
+		ProjectID: "p",
+	}
+	e := log.WithFields(log.Fields{
+		"requestId":    "r1",
+		"userName":     "walrus",
+		"user_name":    "kept",
+		"traceId":      "abc",
+		httpRequestKey: &HTTPRequest{Method: "GET"},
+	})
+
+	entry := formatToMap(t, formatter, e)
+	if entry["request_id"] != "r1" {
+		t.Errorf("request_id = %v, want r1", entry["request_id"])
+	}
+	if entry["user_name"] != "kept" || entry["userName"] != "walrus" {
+		t.Errorf("Got user_name %v and userName %v, want clashing key left intact", entry["user_name"], entry["userName"])
+	}
+	if entry[traceKey] != "projects/p/traces/abc" {
+		t.Errorf("%s = %v, want projects/p/traces/abc", traceKey, entry[traceKey])
+	}
+	if _, ok := entry[httpRequestKey]; !ok {
+		t.Errorf("%s was renamed", httpRequestKey)
+	}
+}
+
+func TestKeyCasingSplitTimestamp(t *testing.T) {
+	formatter := &Formatter{KeyCasing: KeyCasingSnake, TimestampFormat: TimestampFormatSplit}
+	entry := formatToMap(t, formatter, log.WithField("a", 1))
+	for _, k := range []string{"timestampSeconds", "timestampNanos"} {
+		if _, ok := entry[k]; !ok {
+			t.Errorf("%s missing with KeyCasingSnake: %v", k, entry)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package appengine
+
+import (
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CloneEntry returns a deep copy of the entry that can be safely modified or
+// formatted concurrently with the original: Data map and Caller are copied,
+// Buffer is not shared, and maps, slices and arrays in field values are
+// copied recursively. Values referenced by pointers, including errors, are
+// shared with the original, as are maps and slices inside structs. Maps and
+// slices referencing themselves are copied with the same cycles.
+func CloneEntry(e *log.Entry) *log.Entry {
+	c := *e
+	c.Data = make(log.Fields, len(e.Data))
+	for k, v := range e.Data {
+		c.Data[k] = deepCopy(v)
+	}
+	c.Buffer = nil
+	if e.Caller != nil {
+		caller := *e.Caller
+		c.Caller = &caller
+	}
+	return &c
+}
+
+// CloneFields returns a shallow copy of the fields map.
+func CloneFields(fields log.Fields) log.Fields {
+	c := make(log.Fields, len(fields))
+	for k, v := range fields {
+		c[k] = v
+	}
+	return c
+}
+
+// deepCopy returns a copy of v with maps, slices and arrays copied
+// recursively.
+func deepCopy(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, string, bool, int, int64, float64:
+		return v
+	}
+	return deepCopyValue(reflect.ValueOf(v), map[copiedRef]reflect.Value{}).Interface()
+}
+
+// copiedRef identifies a map or a slice already copied by deepCopyValue.
+// Slices sharing the backing array differ in length or type.
+type copiedRef struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// deepCopyValue copies v recursively. copied holds the copies of maps and
+// slices made so far, so that cycles are copied instead of followed
+// forever.
+func deepCopyValue(v reflect.Value, copied map[copiedRef]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopyValue(v.Elem(), copied))
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		ref := copiedRef{typ: v.Type(), ptr: v.Pointer()}
+		if c, ok := copied[ref]; ok {
+			return c
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		copied[ref] = c
+		for it := v.MapRange(); it.Next(); {
+			c.SetMapIndex(it.Key(), deepCopyValue(it.Value(), copied))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		ref := copiedRef{typ: v.Type(), ptr: v.Pointer(), len: v.Len()}
+		if c, ok := copied[ref]; ok {
+			return c
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		copied[ref] = c
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i), copied))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i), copied))
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,82 @@
+package appengine
+
+import (
+	"bytes"
+	"reflect"
+	"runtime"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestCloneEntry(t *testing.T) {
+	e := log.WithField("a", 1)
+	e.Buffer = &bytes.Buffer{}
+	e.Caller = &runtime.Frame{Function: "somefunc"}
+	e.Message = "hello"
+
+	c := CloneEntry(e)
+	c.Data["b"] = 2
+	c.Caller.Function = "otherfunc"
+
+	if _, set := e.Data["b"]; set {
+		t.Error("Data map is shared with the clone")
+	}
+	if e.Caller.Function != "somefunc" {
+		t.Error("Caller is shared with the clone")
+	}
+	if c.Buffer != nil {
+		t.Error("Buffer is shared with the clone")
+	}
+	if c.Message != "hello" || c.Data["a"] != 1 {
+		t.Errorf("Clone does not match the original: %+v", c)
+	}
+}
+
+func TestCloneEntryNested(t *testing.T) {
+	nested := map[string]interface{}{"list": []interface{}{"a", map[string]string{"k": "v"}}}
+	ids := []int{1, 2}
+	e := log.WithFields(log.Fields{"req": nested, "ids": ids, "raw": []byte("raw"), "none": []string(nil)})
+
+	c := CloneEntry(e)
+	nested["added"] = true
+	nested["list"].([]interface{})[1].(map[string]string)["k"] = "changed"
+	ids[0] = 100
+
+	want := log.Fields{
+		"req":  map[string]interface{}{"list": []interface{}{"a", map[string]string{"k": "v"}}},
+		"ids":  []int{1, 2},
+		"raw":  []byte("raw"),
+		"none": []string(nil),
+	}
+	if !reflect.DeepEqual(c.Data, want) {
+		t.Errorf("Clone changed together with the original: got %v, want %v", c.Data, want)
+	}
+}
+
+func TestCloneEntryCycle(t *testing.T) {
+	m := map[string]interface{}{"k": "v"}
+	m["self"] = m
+	s := []interface{}{"a", nil}
+	s[1] = s
+	e := log.WithFields(log.Fields{"map": m, "slice": s})
+
+	c := CloneEntry(e)
+
+	cm := c.Data["map"].(map[string]interface{})
+	cm["k"] = "changed"
+	if m["k"] != "v" {
+		t.Error("Cyclic map is shared with the clone")
+	}
+	if self := cm["self"].(map[string]interface{}); self["k"] != "changed" {
+		t.Errorf("Cycle of the map is not preserved, got %v", self["k"])
+	}
+	cs := c.Data["slice"].([]interface{})
+	cs[0] = "changed"
+	if s[0] != "a" {
+		t.Error("Cyclic slice is shared with the clone")
+	}
+	if self := cs[1].([]interface{}); self[0] != "changed" {
+		t.Errorf("Cycle of the slice is not preserved, got %v", self[0])
+	}
+}
@@ -0,0 +1,24 @@
+package appengine
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UseCloudFunctions configures logger for Cloud Functions (2nd gen) and
+// returns a wrapper for HTTP functions, which makes entries logged with the
+// request context (see FromContext) correlated with the request trace:
+//
+//	wrap := appengine.UseCloudFunctions(logrus.StandardLogger())
+//	functions.HTTP("Hello", wrap(hello))
+//
+// The logger gets a Formatter with EnvironmentLabels enabled, which adds
+// function name and revision labels, and "serviceContext" detected from
+// K_SERVICE and K_REVISION to errors.
+func UseCloudFunctions(logger *log.Logger) func(http.HandlerFunc) http.HandlerFunc {
+	logger.SetFormatter(&Formatter{EnvironmentLabels: true})
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return Middleware(fn).ServeHTTP
+	}
+}
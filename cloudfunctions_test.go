@@ -0,0 +1,50 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestUseCloudFunctions(t *testing.T) {
+	defer func(d func() map[string]string) { environmentLabelsDetector = d }(environmentLabelsDetector)
+	environmentLabelsDetector = func() map[string]string {
+		return map[string]string{"function_name": "hello", "function_target": "Hello"}
+	}
+	defer func(d func() *ServiceContext) { serviceContextDetector = d }(serviceContextDetector)
+	serviceContextDetector = func() *ServiceContext {
+		return &ServiceContext{Service: "hello", Version: "hello-00001"}
+	}
+
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	wrap := UseCloudFunctions(logger)
+	fn := wrap(func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).Log(log.ErrorLevel, "hello")
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Cloud-Trace-Context", testTraceID+"/1;o=1")
+	fn(httptest.NewRecorder(), r)
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if entry[spanIDKey] != "0000000000000001" {
+		t.Errorf("%s = %v, want span from the request", spanIDKey, entry[spanIDKey])
+	}
+	want := map[string]interface{}{"function_name": "hello", "function_target": "Hello"}
+	if !reflect.DeepEqual(entry[labelsKey], want) {
+		t.Errorf("%s = %v, want %v", labelsKey, entry[labelsKey], want)
+	}
+	if sc, _ := entry["serviceContext"].(map[string]interface{}); sc["service"] != "hello" || sc["version"] != "hello-00001" {
+		t.Errorf("serviceContext = %v, want the detected one", entry["serviceContext"])
+	}
+}
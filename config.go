@@ -0,0 +1,158 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Config holds Formatter settings loaded from a JSON file, so logging can
+// be tuned per environment without a rebuild. Example:
+//
+//	{
+//	  "projectId": "my-project",
+//	  "serviceContext": {"service": "api", "version": "v1"},
+//	  "labels": {"env": "prod"},
+//	  "fieldMap": {"message": "msg"},
+//	  "severityMap": {"warning": "NOTICE"},
+//	  "minSeverity": "INFO",
+//	  "redactKeys": ["password"]
+//	}
+type Config struct {
+	ProjectID      string            `json:"projectId"`
+	TraceProjectID string            `json:"traceProjectId"`
+	ServiceContext *ServiceContext   `json:"serviceContext"`
+	Labels         map[string]string `json:"labels"`
+	// FieldMap maps FieldKey* constants to output keys.
+	FieldMap map[string]string `json:"fieldMap"`
+	// SeverityMap maps logrus level names, e.g. "warning", to severities.
+	SeverityMap       map[string]Severity `json:"severityMap"`
+	MinSeverity       Severity            `json:"minSeverity"`
+	RedactKeys        []string            `json:"redactKeys"`
+	PrettyPrint       bool                `json:"prettyPrint"`
+	DisableTimestamp  bool                `json:"disableTimestamp"`
+	DisableLevelField bool                `json:"disableLevelField"`
+	DevMode           bool                `json:"devMode"`
+}
+
+// LoadConfig reads Config from the JSON file. Unknown keys and severities
+// are rejected to catch typos.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config, %v", err)
+	}
+	return ParseConfig(b)
+}
+
+// ParseConfig parses Config from JSON. Unknown keys and severities are
+// rejected to catch typos.
+func ParseConfig(b []byte) (*Config, error) {
+	c := &Config{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(c); err != nil {
+		return nil, fmt.Errorf("failed to parse config, %v", err)
+	}
+	if _, _, err := c.severities(); err != nil {
+		return nil, fmt.Errorf("failed to parse config, %v", err)
+	}
+	if err := c.checkFieldMap(); err != nil {
+		return nil, fmt.Errorf("failed to parse config, %v", err)
+	}
+	return c, nil
+}
+
+// severities returns SeverityMap converted to logrus levels, and
+// MinSeverity, with severity names normalized.
+func (c *Config) severities() (map[log.Level]Severity, Severity, error) {
+	var severityMap map[log.Level]Severity
+	if len(c.SeverityMap) > 0 {
+		severityMap = make(map[log.Level]Severity, len(c.SeverityMap))
+		for name, s := range c.SeverityMap {
+			l, err := log.ParseLevel(name)
+			if err != nil {
+				return nil, "", fmt.Errorf("severityMap: %v", err)
+			}
+			sev, err := severityFromName(string(s))
+			if err != nil {
+				return nil, "", fmt.Errorf("severityMap: %v", err)
+			}
+			severityMap[l] = sev
+		}
+	}
+	var min Severity
+	if c.MinSeverity != "" {
+		s, err := severityFromName(string(c.MinSeverity))
+		if err != nil {
+			return nil, "", fmt.Errorf("minSeverity: %v", err)
+		}
+		min = s
+	}
+	return severityMap, min, nil
+}
+
+// checkFieldMap returns an error if FieldMap has keys other than FieldKey*
+// constants.
+func (c *Config) checkFieldMap() error {
+	for k := range c.FieldMap {
+		known := false
+		for _, fk := range fieldKeys {
+			if k == fk {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("fieldMap: unknown key %q", k)
+		}
+	}
+	return nil
+}
+
+// Option returns an Option applying the settings of the config to a
+// Formatter. Settings missing from the config are left unchanged. Returns
+// an error if the config has unknown severities, logrus levels or FieldMap
+// keys, e.g. if it wasn't loaded with LoadConfig or ParseConfig.
+func (c *Config) Option() (Option, error) {
+	severityMap, minSeverity, err := c.severities()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config, %v", err)
+	}
+	if err := c.checkFieldMap(); err != nil {
+		return nil, fmt.Errorf("invalid config, %v", err)
+	}
+	return func(f *Formatter) {
+		if c.ProjectID != "" {
+			f.ProjectID = c.ProjectID
+		}
+		if c.TraceProjectID != "" {
+			f.TraceProjectID = c.TraceProjectID
+		}
+		if c.ServiceContext != nil {
+			f.ServiceContext = c.ServiceContext
+		}
+		if c.Labels != nil {
+			WithDefaultLabels(c.Labels)(f)
+		}
+		if c.FieldMap != nil {
+			f.FieldMap = FieldMap(c.FieldMap)
+		}
+		if severityMap != nil {
+			f.SeverityMap = severityMap
+		}
+		if minSeverity != "" {
+			f.MinSeverity = minSeverity
+		}
+		if c.RedactKeys != nil {
+			f.RedactKeys = c.RedactKeys
+		}
+		f.PrettyPrint = f.PrettyPrint || c.PrettyPrint
+		f.DisableTimestamp = f.DisableTimestamp || c.DisableTimestamp
+		f.DisableLevelField = f.DisableLevelField || c.DisableLevelField
+		f.DevMode = f.DevMode || c.DevMode
+	}, nil
+}
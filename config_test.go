@@ -0,0 +1,104 @@
+package appengine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logging.json")
+	config := `{
+		"projectId": "my-project",
+		"serviceContext": {"service": "api", "version": "v1"},
+		"labels": {"env": "prod"},
+		"fieldMap": {"message": "msg"},
+		"severityMap": {"warning": "notice"},
+		"minSeverity": "info",
+		"redactKeys": ["password"],
+		"disableLevelField": true
+	}`
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	opt, err := c.Option()
+	if err != nil {
+		t.Fatalf("Option() failed: %v", err)
+	}
+	f := New(WithDefaultLabels(map[string]string{"team": "core"}), opt)
+	if f.ProjectID != "my-project" || f.MinSeverity != SeverityInfo || !f.DisableLevelField {
+		t.Errorf("ProjectID = %q, MinSeverity = %q, DisableLevelField = %v", f.ProjectID, f.MinSeverity, f.DisableLevelField)
+	}
+	if want := map[string]string{"env": "prod", "team": "core"}; !reflect.DeepEqual(f.Labels, want) {
+		t.Errorf("Labels = %v, want %v", f.Labels, want)
+	}
+	if want := map[log.Level]Severity{log.WarnLevel: SeverityNotice}; !reflect.DeepEqual(f.SeverityMap, want) {
+		t.Errorf("SeverityMap = %v, want %v", f.SeverityMap, want)
+	}
+
+	e := log.WithField("Password", "hunter2")
+	e.Level = log.WarnLevel
+	e.Message = "login"
+	entry := formatToMap(t, f, e)
+	want := map[string]interface{}{"msg": "login", "severity": "NOTICE", "Password": "[REDACTED]"}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		`{"projectName": "typo"}`,
+		`{"severityMap": {"loud": "ERROR"}}`,
+		`{"severityMap": {"warning": "WARN"}}`,
+		`{"minSeverity": "warn"}`,
+		`{"fieldMap": {"msg": "message"}}`,
+		`not json`,
+	} {
+		if _, err := ParseConfig([]byte(config)); err == nil {
+			t.Errorf("ParseConfig(%s) succeeded, want error", config)
+		}
+	}
+}
+
+func TestConfigOption(t *testing.T) {
+	c := &Config{
+		SeverityMap: map[string]Severity{"warning": "notice"},
+		MinSeverity: "info",
+	}
+	opt, err := c.Option()
+	if err != nil {
+		t.Fatalf("Option() failed: %v", err)
+	}
+	f := New(opt)
+	if want := map[log.Level]Severity{log.WarnLevel: SeverityNotice}; !reflect.DeepEqual(f.SeverityMap, want) || f.MinSeverity != SeverityInfo {
+		t.Errorf("SeverityMap = %v, MinSeverity = %q, want %v and INFO", f.SeverityMap, f.MinSeverity, want)
+	}
+
+	for _, c := range []*Config{
+		{SeverityMap: map[string]Severity{"loud": SeverityError}},
+		{SeverityMap: map[string]Severity{"warning": "WARN"}},
+		{MinSeverity: "warn"},
+		{FieldMap: map[string]string{"msg": "message"}},
+	} {
+		if _, err := c.Option(); err == nil {
+			t.Errorf("Option() of %+v succeeded, want error", c)
+		}
+	}
+}
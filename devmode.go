@@ -0,0 +1,85 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// devTimeFormat is the timestamp layout of DevMode output.
+const devTimeFormat = "15:04:05.000"
+
+// severityColor returns ANSI color code for the severity in DevMode output.
+func severityColor(s Severity) int {
+	switch {
+	case s.rank() >= SeverityError.rank():
+		return 31 // red
+	case s.rank() >= SeverityWarning.rank():
+		return 33 // yellow
+	case s.rank() >= SeverityInfo.rank():
+		return 36 // cyan
+	default:
+		return 37 // gray
+	}
+}
+
+// formatDev renders data as a single colorized line: time, severity,
+// source location and message followed by the rest of the fields in sorted
+// order. Keys consumed by the formatter are removed from data.
+func (f *Formatter) formatDev(b *bytes.Buffer, t time.Time, severity Severity, data log.Fields) error {
+	for _, k := range []string{FieldKeyTimestamp, FieldKeyTime, FieldKeyTimestampSeconds, FieldKeyTimestampNanos, FieldKeySeverity, FieldKeyLevel} {
+		delete(data, f.FieldMap.resolve(k))
+	}
+	if !f.DisableTimestamp {
+		b.WriteString(t.Format(devTimeFormat))
+		b.WriteByte(' ')
+	}
+	fmt.Fprintf(b, "\x1b[%dm%-8s\x1b[0m", severityColor(severity), severity)
+	if l, ok := data[f.FieldMap.resolve(FieldKeySourceLocation)].(map[string]interface{}); ok {
+		delete(data, f.FieldMap.resolve(FieldKeySourceLocation))
+		if file, ok := l["file"].(string); ok {
+			fmt.Fprintf(b, " %s:%v", file, l["line"])
+		} else if fn, ok := l["function"].(string); ok {
+			fmt.Fprintf(b, " %s", fn)
+		}
+	}
+	msgKey := f.FieldMap.resolve(FieldKeyMessage)
+	fmt.Fprintf(b, " %v", data[msgKey])
+	delete(data, msgKey)
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, err := devValue(data[k])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, " \x1b[%dm%s\x1b[0m=%s", severityColor(severity), k, v)
+	}
+	b.WriteByte('\n')
+	return nil
+}
+
+// devValue renders a field value for DevMode output. Strings are quoted
+// only if needed, other values are encoded as JSON.
+func devValue(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+			return fmt.Sprintf("%q", s), nil
+		}
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
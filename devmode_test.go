@@ -0,0 +1,32 @@
+package appengine
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestDevMode(t *testing.T) {
+	log.SetReportCaller(true)
+	defer log.SetReportCaller(false)
+
+	e := log.WithFields(log.Fields{"user": "alice", "note": "two words", "n": 3, "trace": "abc"})
+	e.Time = time.Date(2019, 3, 10, 7, 30, 0, 123456789, time.Local)
+	e.Level = log.WarnLevel
+	e.Message = "hello"
+	e.Caller = &runtime.Frame{Function: "main.handler", File: "/src/main.go", Line: 10}
+	b, err := (&Formatter{DevMode: true, TraceKey: "trace", ProjectID: "my-project"}).Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+	want := "07:30:00.123 \x1b[33mWARNING \x1b[0m /src/main.go:10 hello" +
+		" \x1b[33mlogging.googleapis.com/trace\x1b[0m=projects/my-project/traces/abc" +
+		" \x1b[33mn\x1b[0m=3" +
+		" \x1b[33mnote\x1b[0m=\"two words\"" +
+		" \x1b[33muser\x1b[0m=alice\n"
+	if string(b) != want {
+		t.Errorf("Format() = %q, want %q", b, want)
+	}
+}
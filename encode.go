@@ -0,0 +1,107 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HighSignalFieldOrder is a FieldOrder value that puts keys most useful for
+// humans reading raw logs at the beginning of every entry.
+var HighSignalFieldOrder = []string{
+	"severity",
+	"message",
+	"logging.googleapis.com/trace",
+	"logging.googleapis.com/spanId",
+}
+
+// Interner holds JSON encodings of frequently repeated strings, such as
+// status names or endpoint paths, so they are escaped once instead of for
+// every entry. It is used for keys and top-level string values. Interner
+// is immutable and safe for concurrent use.
+type Interner struct {
+	encoded map[string][]byte
+}
+
+// NewInterner returns an Interner for the given values.
+func NewInterner(values ...string) *Interner {
+	in := &Interner{encoded: make(map[string][]byte, len(values))}
+	for _, v := range values {
+		// Marshaling a string never fails.
+		in.encoded[v], _ = json.Marshal(v)
+	}
+	return in
+}
+
+// writeString writes JSON encoding of s into b, reusing the interned one if
+// there's any.
+func (in *Interner) writeString(b *bytes.Buffer, s string) error {
+	if in != nil {
+		if e, ok := in.encoded[s]; ok {
+			b.Write(e)
+			return nil
+		}
+	}
+	e, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	b.Write(e)
+	return nil
+}
+
+// encodeObject writes data into b as a JSON object followed by a newline.
+// Keys listed in order come first, in the given order, and the rest are
+// sorted, same as encoding/json does for maps. in may be nil.
+func encodeObject(b *bytes.Buffer, data log.Fields, order []string, pretty bool, in *Interner) error {
+	keys := make([]string, 0, len(data))
+	seen := make(map[string]bool, len(order))
+	for _, k := range order {
+		if _, ok := data[k]; ok && !seen[k] {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+	}
+	ordered := len(keys)
+	for k := range data {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[ordered:])
+
+	out := b
+	if pretty {
+		out = &bytes.Buffer{}
+	}
+	out.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		if err := in.writeString(out, k); err != nil {
+			return err
+		}
+		out.WriteByte(':')
+		if v, ok := data[k].(string); ok && in != nil {
+			if err := in.writeString(out, v); err != nil {
+				return err
+			}
+			continue
+		}
+		vb, err := json.Marshal(data[k])
+		if err != nil {
+			return err
+		}
+		out.Write(vb)
+	}
+	out.WriteByte('}')
+	if pretty {
+		if err := json.Indent(b, out.Bytes(), "", "  "); err != nil {
+			return err
+		}
+	}
+	return b.WriteByte('\n')
+}
@@ -0,0 +1,64 @@
+package appengine
+
+import (
+	"bytes"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestEncodeObjectMatchesEncodingJSON(t *testing.T) {
+	data := log.Fields{"b": 1, "a": "<x>", "c": []int{1, 2}}
+
+	b := &bytes.Buffer{}
+	if err := encodeObject(b, data, nil, false, nil); err != nil {
+		t.Fatal("encodeObject failed: ", err)
+	}
+	if want := "{\"a\":\"\\u003cx\\u003e\",\"b\":1,\"c\":[1,2]}\n"; b.String() != want {
+		t.Errorf("Got %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := encodeObject(b, data, nil, true, nil); err != nil {
+		t.Fatal("encodeObject failed: ", err)
+	}
+	if want := "{\n  \"a\": \"\\u003cx\\u003e\",\n  \"b\": 1,\n  \"c\": [\n    1,\n    2\n  ]\n}\n"; b.String() != want {
+		t.Errorf("Got %q, want %q", b.String(), want)
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	formatter := &Formatter{
+		DisableTimestamp: true,
+		FieldOrder:       HighSignalFieldOrder,
+	}
+
+	e := log.WithField("aaa", "first alphabetically")
+	e.Message = "hello"
+	b, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+
+	want := `{"severity":"CRITICAL","message":"hello","aaa":"first alphabetically","level":"panic"}` + "\n"
+	if string(b) != want {
+		t.Errorf("Got %s, want %s", b, want)
+	}
+}
+
+func TestInterner(t *testing.T) {
+	in := NewInterner("path", "/api/<v1>", "severity")
+	data := log.Fields{"path": "/api/<v1>", "other": "/api/<v1>", "n": 1, "severity": SeverityInfo}
+
+	want := &bytes.Buffer{}
+	if err := encodeObject(want, data, nil, false, nil); err != nil {
+		t.Fatal("encodeObject failed: ", err)
+	}
+	b := &bytes.Buffer{}
+	if err := encodeObject(b, data, nil, false, in); err != nil {
+		t.Fatal("encodeObject failed: ", err)
+	}
+	if b.String() != want.String() {
+		t.Errorf("Got %q with Interner, want %q", b.String(), want.String())
+	}
+}
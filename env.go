@@ -0,0 +1,102 @@
+package appengine
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NewFromEnv returns a Formatter configured with the given options and then
+// with the following environment variables, if set, and starts project ID
+// detection like New does:
+//
+//	LOG_PROJECT_ID         ProjectID
+//	LOG_TRACE_PROJECT_ID   TraceProjectID
+//	LOG_SERVICE            ServiceContext.Service
+//	LOG_VERSION            ServiceContext.Version
+//	LOG_LABELS             Labels, as comma-separated key=value pairs
+//	LOG_MIN_SEVERITY       MinSeverity
+//	LOG_PRETTY             PrettyPrint
+//	LOG_DISABLE_TIMESTAMP  DisableTimestamp
+//	LOG_DEV_MODE           DevMode
+//
+// Boolean variables accept the values accepted by strconv.ParseBool.
+// LOG_MIN_SEVERITY accepts severity names in any case, other values are
+// rejected with an error.
+func NewFromEnv(opts ...Option) (*Formatter, error) {
+	f := newFormatter(opts...)
+	if v := os.Getenv("LOG_PROJECT_ID"); v != "" {
+		f.ProjectID = v
+	}
+	if v := os.Getenv("LOG_TRACE_PROJECT_ID"); v != "" {
+		f.TraceProjectID = v
+	}
+	service, version := os.Getenv("LOG_SERVICE"), os.Getenv("LOG_VERSION")
+	if service != "" || version != "" {
+		sc := ServiceContext{}
+		if f.ServiceContext != nil {
+			sc = *f.ServiceContext
+		}
+		if service != "" {
+			sc.Service = service
+		}
+		if version != "" {
+			sc.Version = version
+		}
+		f.ServiceContext = &sc
+	}
+	if v := os.Getenv("LOG_LABELS"); v != "" {
+		labels, err := parseLabels(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse LOG_LABELS, %v", err)
+		}
+		WithDefaultLabels(labels)(f)
+	}
+	if v := os.Getenv("LOG_MIN_SEVERITY"); v != "" {
+		s, err := severityFromName(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse LOG_MIN_SEVERITY, %v", err)
+		}
+		f.MinSeverity = s
+	}
+	for _, b := range []struct {
+		name  string
+		value *bool
+	}{
+		{"LOG_PRETTY", &f.PrettyPrint},
+		{"LOG_DISABLE_TIMESTAMP", &f.DisableTimestamp},
+		{"LOG_DEV_MODE", &f.DevMode},
+	} {
+		v := os.Getenv(b.name)
+		if v == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s, %v", b.name, err)
+		}
+		*b.value = parsed
+	}
+	f.startDetection()
+	return f, nil
+}
+
+// parseLabels parses comma-separated key=value pairs.
+func parseLabels(s string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, kv := range strings.Split(s, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		if !strings.Contains(kv, "=") {
+			return nil, fmt.Errorf("invalid label %q, want key=value", kv)
+		}
+		k, v := splitOnce(kv, "=")
+		if strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid label %q, want key=value", kv)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
@@ -0,0 +1,50 @@
+package appengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	defer setenv("LOG_PROJECT_ID", "my-project")()
+	defer setenv("LOG_VERSION", "v2")()
+	defer setenv("LOG_LABELS", "env=prod, region=eu")()
+	defer setenv("LOG_MIN_SEVERITY", "warning")()
+	defer setenv("LOG_PRETTY", "true")()
+	defer setenv("LOG_DISABLE_TIMESTAMP", "1")()
+
+	f, err := NewFromEnv(WithServiceContext("api", "v1"), WithDefaultLabels(map[string]string{"env": "dev", "team": "core"}))
+	if err != nil {
+		t.Fatalf("NewFromEnv() failed: %v", err)
+	}
+	if f.ProjectID != "my-project" {
+		t.Errorf("ProjectID = %q, want my-project", f.ProjectID)
+	}
+	if want := (&ServiceContext{Service: "api", Version: "v2"}); !reflect.DeepEqual(f.ServiceContext, want) {
+		t.Errorf("ServiceContext = %+v, want %+v", f.ServiceContext, want)
+	}
+	if want := map[string]string{"env": "prod", "region": "eu", "team": "core"}; !reflect.DeepEqual(f.Labels, want) {
+		t.Errorf("Labels = %v, want %v", f.Labels, want)
+	}
+	if f.MinSeverity != SeverityWarning {
+		t.Errorf("MinSeverity = %q, want WARNING", f.MinSeverity)
+	}
+	if !f.PrettyPrint || !f.DisableTimestamp || f.DevMode {
+		t.Errorf("PrettyPrint = %v, DisableTimestamp = %v, DevMode = %v, want true, true, false", f.PrettyPrint, f.DisableTimestamp, f.DevMode)
+	}
+}
+
+func TestNewFromEnvErrors(t *testing.T) {
+	for _, test := range []struct{ name, value string }{
+		{"LOG_PRETTY", "maybe"},
+		{"LOG_LABELS", "env"},
+		{"LOG_LABELS", "=prod"},
+		{"LOG_MIN_SEVERITY", "warn"},
+	} {
+		restore := setenv(test.name, test.value)
+		if _, err := NewFromEnv(); err == nil {
+			t.Errorf("NewFromEnv() with %s=%q succeeded, want error", test.name, test.value)
+		}
+		restore()
+	}
+}
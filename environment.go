@@ -0,0 +1,103 @@
+package appengine
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	environmentLabelsOnce sync.Once
+	environmentLabels     map[string]string
+
+	// environmentLabelsDetector is used by Formatter when EnvironmentLabels
+	// is set. Replaced in tests.
+	environmentLabelsDetector = DetectEnvironmentLabels
+)
+
+// DetectEnvironmentLabels returns labels describing the environment the
+// program is running in, detected from environment variables. On App Engine
+// these are "module_id", "version_id", "instance_name" and "runtime", and on
+// Cloud Run "service_name", "revision_name" and "configuration_name". On
+// Cloud Functions (2nd gen) these are "function_name", "function_target" and
+// "revision_name". On GKE these are "cluster_name", "location",
+// "namespace_name", "pod_name" and "container_name", taken from
+// CLUSTER_NAME, CLUSTER_LOCATION, POD_NAMESPACE, POD_NAME and CONTAINER_NAME
+// environment variables (set them with the downward API), the service
+// account namespace file and HOSTNAME. The metadata server is never queried,
+// use GKEDetector for that. The result is cached for the lifetime of the
+// process. Returns nil if the environment is not recognized.
+func DetectEnvironmentLabels() map[string]string {
+	environmentLabelsOnce.Do(func() {
+		environmentLabels = detectEnvironmentLabels()
+	})
+	return environmentLabels
+}
+
+func detectEnvironmentLabels() map[string]string {
+	// Only the detectors that don't need network access are used, since
+	// this may run on the first Format call.
+	r := DetectResource(AppEngineDetector, CloudFunctionsDetector, CloudRunDetector, ResourceDetectorFunc(detectGKEFromEnvironment))
+	if r == nil {
+		return nil
+	}
+	return r.Labels
+}
+
+// namespaceFile holds the namespace of the pod. Replaced in tests.
+var namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// gkeLabels returns labels of the GKE container. Cluster name and location
+// missing from the environment are queried from the metadata server if
+// useMetadata is set.
+func gkeLabels(useMetadata bool) map[string]string {
+	labels := map[string]string{
+		"cluster_name":   os.Getenv("CLUSTER_NAME"),
+		"location":       os.Getenv("CLUSTER_LOCATION"),
+		"namespace_name": os.Getenv("POD_NAMESPACE"),
+		"pod_name":       os.Getenv("POD_NAME"),
+		"container_name": os.Getenv("CONTAINER_NAME"),
+	}
+	if labels["namespace_name"] == "" {
+		if b, err := ioutil.ReadFile(namespaceFile); err == nil {
+			labels["namespace_name"] = strings.TrimSpace(string(b))
+		}
+	}
+	if labels["pod_name"] == "" {
+		labels["pod_name"] = os.Getenv("HOSTNAME")
+	}
+	if useMetadata && labels["cluster_name"] == "" {
+		labels["cluster_name"], _ = metadataValue("instance/attributes/cluster-name")
+	}
+	if useMetadata && labels["location"] == "" {
+		labels["location"], _ = metadataValue("instance/attributes/cluster-location")
+	}
+	return labels
+}
+
+// nonEmptyLabels returns labels with empty values removed.
+func nonEmptyLabels(labels map[string]string) map[string]string {
+	for k, v := range labels {
+		if v == "" {
+			delete(labels, k)
+		}
+	}
+	return labels
+}
+
+// environmentLabels returns labels of the resource detected with
+// ResourceDetectors, or detected environment labels if EnvironmentLabels is
+// set.
+func (f *Formatter) environmentLabels() map[string]string {
+	if r := f.resource(); r != nil {
+		return r.Labels
+	}
+	if !f.EnvironmentLabels {
+		return nil
+	}
+	f.environmentOnce.Do(func() {
+		f.detectedEnvironment = environmentLabelsDetector()
+	})
+	return f.detectedEnvironment
+}
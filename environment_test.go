@@ -0,0 +1,172 @@
+package appengine
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestDetectEnvironmentLabels(t *testing.T) {
+	for _, v := range []string{"GAE_SERVICE", "GAE_VERSION", "GAE_INSTANCE", "GAE_RUNTIME", "K_SERVICE", "K_REVISION", "K_CONFIGURATION", "FUNCTION_TARGET", "KUBERNETES_SERVICE_HOST"} {
+		defer setenv(v, "")()
+	}
+	if labels := detectEnvironmentLabels(); labels != nil {
+		t.Errorf("detectEnvironmentLabels() = %v, want nil", labels)
+	}
+
+	defer setenv("K_SERVICE", "run-service")()
+	defer setenv("K_REVISION", "run-service-00001")()
+	want := map[string]string{"service_name": "run-service", "revision_name": "run-service-00001"}
+	if labels := detectEnvironmentLabels(); !reflect.DeepEqual(labels, want) {
+		t.Errorf("detectEnvironmentLabels() = %v, want %v", labels, want)
+	}
+
+	defer setenv("K_CONFIGURATION", "run-service")()
+	want["configuration_name"] = "run-service"
+	if labels := detectEnvironmentLabels(); !reflect.DeepEqual(labels, want) {
+		t.Errorf("detectEnvironmentLabels() = %v, want %v", labels, want)
+	}
+
+	defer setenv("FUNCTION_TARGET", "Hello")()
+	want = map[string]string{"function_name": "run-service", "function_target": "Hello", "revision_name": "run-service-00001"}
+	if labels := detectEnvironmentLabels(); !reflect.DeepEqual(labels, want) {
+		t.Errorf("detectEnvironmentLabels() = %v, want %v", labels, want)
+	}
+
+	defer setenv("GAE_SERVICE", "default")()
+	defer setenv("GAE_VERSION", "20190101t000000")()
+	defer setenv("GAE_INSTANCE", "00c61b117c")()
+	defer setenv("GAE_RUNTIME", "go112")()
+	want = map[string]string{
+		"module_id":     "default",
+		"version_id":    "20190101t000000",
+		"instance_name": "00c61b117c",
+		"runtime":       "go112",
+	}
+	if labels := detectEnvironmentLabels(); !reflect.DeepEqual(labels, want) {
+		t.Errorf("detectEnvironmentLabels() = %v, want %v", labels, want)
+	}
+}
+
+func TestEnvironmentLabels(t *testing.T) {
+	defer func(d func() map[string]string) { environmentLabelsDetector = d }(environmentLabelsDetector)
+	environmentLabelsDetector = func() map[string]string {
+		return map[string]string{"service_name": "run-service", "revision_name": "r1"}
+	}
+
+	formatter := &Formatter{EnvironmentLabels: true, Labels: map[string]string{"revision_name": "static"}}
+	entry := formatToMap(t, formatter, log.WithField("a", 1))
+	want := map[string]interface{}{"service_name": "run-service", "revision_name": "static"}
+	if !reflect.DeepEqual(entry[labelsKey], want) {
+		t.Errorf("%s = %v, want %v", labelsKey, entry[labelsKey], want)
+	}
+
+	entry = formatToMap(t, &Formatter{}, log.WithField("a", 1))
+	if _, ok := entry[labelsKey]; ok {
+		t.Errorf("%s = %v without EnvironmentLabels", labelsKey, entry[labelsKey])
+	}
+}
+
+func TestEnvironmentLabelsServiceContext(t *testing.T) {
+	defer func(d func() *ServiceContext) { serviceContextDetector = d }(serviceContextDetector)
+	serviceContextDetector = func() *ServiceContext {
+		return &ServiceContext{Service: "run-service", Version: "r1"}
+	}
+
+	e := log.NewEntry(log.New())
+	e.Level = log.ErrorLevel
+	entry := formatToMap(t, &Formatter{EnvironmentLabels: true}, e)
+	want := map[string]interface{}{"service": "run-service", "version": "r1"}
+	if !reflect.DeepEqual(entry["serviceContext"], want) {
+		t.Errorf("serviceContext = %v, want %v", entry["serviceContext"], want)
+	}
+	if entry = formatToMap(t, &Formatter{}, e); entry["serviceContext"] != nil {
+		t.Errorf("serviceContext = %v without EnvironmentLabels", entry["serviceContext"])
+	}
+}
+
+func TestDetectGKELabels(t *testing.T) {
+	for _, v := range []string{"GAE_SERVICE", "K_SERVICE", "FUNCTION_TARGET", "CLUSTER_NAME", "CLUSTER_LOCATION", "POD_NAMESPACE", "POD_NAME"} {
+		defer setenv(v, "")()
+	}
+	defer setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")()
+	defer setenv("HOSTNAME", "web-5d8f7-abcde")()
+	defer setenv("CONTAINER_NAME", "web")()
+
+	dir, err := ioutil.TempDir("", "namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(f string) { namespaceFile = f }(namespaceFile)
+	namespaceFile = filepath.Join(dir, "namespace")
+	if err := ioutil.WriteFile(namespaceFile, []byte("prod\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	queries := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/attributes/cluster-name":
+			w.Write([]byte("my-cluster"))
+		case "/computeMetadata/v1/instance/attributes/cluster-location":
+			w.Write([]byte("europe-west1"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))()
+
+	want := map[string]string{
+		"namespace_name": "prod",
+		"pod_name":       "web-5d8f7-abcde",
+		"container_name": "web",
+	}
+	if labels := detectEnvironmentLabels(); !reflect.DeepEqual(labels, want) {
+		t.Errorf("detectEnvironmentLabels() = %v, want %v", labels, want)
+	}
+	mu.Lock()
+	if queries != 0 {
+		t.Errorf("detectEnvironmentLabels() made %d metadata server queries, want none", queries)
+	}
+	mu.Unlock()
+
+	defer setenv("CLUSTER_LOCATION", "us-east1")()
+	want["location"] = "us-east1"
+	want["cluster_name"] = "my-cluster"
+	r := GKEDetector.Detect()
+	if r == nil || !reflect.DeepEqual(r.Labels, want) {
+		t.Errorf("GKEDetector.Detect() = %v, want labels %v", r, want)
+	}
+}
+
+func TestEnvironmentLabelsAppEngineServiceContext(t *testing.T) {
+	for _, v := range []string{"K_SERVICE", "K_REVISION"} {
+		defer setenv(v, "")()
+	}
+	defer setenv("GAE_SERVICE", "default")()
+	defer setenv("GAE_VERSION", "20190101t000000")()
+	defer func(d func() *ServiceContext) { serviceContextDetector = d }(serviceContextDetector)
+	serviceContextDetector = detectServiceContext
+
+	e := log.NewEntry(log.New())
+	e.Level = log.ErrorLevel
+	entry := formatToMap(t, &Formatter{EnvironmentLabels: true}, e)
+	want := map[string]interface{}{"service": "default", "version": "20190101t000000"}
+	if !reflect.DeepEqual(entry["serviceContext"], want) {
+		t.Errorf("serviceContext = %v, want %v", entry["serviceContext"], want)
+	}
+}
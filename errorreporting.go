@@ -0,0 +1,60 @@
+package appengine
+
+import (
+	"runtime"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// isReportableError reports whether entries with the given severity are
+// meant for Error Reporting.
+func (f *Formatter) isReportableError(severity Severity) bool {
+	min := f.ErrorReportingMinSeverity
+	if min == "" {
+		min = SeverityError
+	}
+	return severity.rank() >= min.rank()
+}
+
+// StackFramer is implemented by errors that carry the stack trace of the
+// place they were created at. Frames are ordered starting from the innermost
+// call.
+type StackFramer interface {
+	StackFrames() []runtime.Frame
+}
+
+// errorOrigin returns the frame where the error was created, taken from the
+// innermost error in the chain that carries a stack trace. Returns nil if
+// there's no such error.
+func errorOrigin(err error) *runtime.Frame {
+	if stack := errorStack(err); len(stack) > 0 {
+		return &stack[0]
+	}
+	return nil
+}
+
+// reportLocation returns Error Reporting's reportLocation for the entry,
+// built from the origin of the attached error or, if not available, from
+// the entry source location. Returns nil if neither is known.
+func (f *Formatter) reportLocation(entry *log.Entry) map[string]interface{} {
+	err, _ := entry.Data[log.ErrorKey].(error)
+	origin := errorOrigin(err)
+	if origin == nil {
+		origin = f.entryCaller(entry)
+	}
+	if origin == nil {
+		return nil
+	}
+	function, file := f.frameLocation(origin)
+	l := map[string]interface{}{}
+	if function != "" {
+		l["functionName"] = function
+	}
+	if file != "" {
+		l["filePath"] = file
+		l["lineNumber"] = origin.Line
+	}
+	return l
+}
@@ -0,0 +1,289 @@
+// Package errorreporting provides a logrus hook that submits entries to the
+// Cloud Error Reporting API directly, for environments where errors are not
+// ingested from logs.
+package errorreporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultEndpoint is the base URL of the Error Reporting API.
+const DefaultEndpoint = "https://clouderrorreporting.googleapis.com"
+
+// Config configures the Hook. Only ProjectID and Client are required.
+type Config struct {
+	// ProjectID is the project to report errors to.
+	ProjectID string
+
+	// Client is used to make API requests. It must add credentials
+	// authorized for https://www.googleapis.com/auth/cloud-platform scope,
+	// e.g. the one returned by golang.org/x/oauth2/google.DefaultClient.
+	Client *http.Client
+
+	// Formatter is used to derive the stack trace, report location, service
+	// context and HTTP request of the reported event. It should have
+	// StackTrace and ErrorReporting options enabled, otherwise the API
+	// accepts only events with a known report location, and report location
+	// and service context are not derived. Options changing the layout or
+	// presence of the output (DevMode, MinSeverity, FieldMap, KeyCasing and
+	// NonBlockingDetection) are not supported. Defaults to Formatter with
+	// both enabled.
+	Formatter *appengine.Formatter
+
+	// Levels of entries to report. Defaults to Error, Fatal and Panic.
+	Levels []log.Level
+
+	// Endpoint overrides DefaultEndpoint.
+	Endpoint string
+
+	// QueueSize is the number of events waiting to be sent. Events are
+	// dropped when the queue is full. Defaults to 1000.
+	QueueSize int
+
+	// MaxAttempts is the number of attempts to send an event, including the
+	// first one. Defaults to 5.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. It's doubled before every
+	// next one. Defaults to 1 second.
+	Backoff time.Duration
+}
+
+// Hook reports entries to Error Reporting. Events are queued and sent one
+// by one in the background, since the API accepts a single event per
+// request, so Fire never blocks on the network.
+type Hook struct {
+	cfg   Config
+	queue chan []byte
+
+	mu      sync.Mutex
+	pending sync.WaitGroup
+	closed  bool
+	done    chan struct{}
+}
+
+// NewHook returns a Hook with the given configuration and starts its
+// background worker. Close must be called to flush pending events on exit.
+func NewHook(cfg Config) (*Hook, error) {
+	if cfg.ProjectID == "" {
+		return nil, errors.New("errorreporting: ProjectID is required")
+	}
+	if cfg.Client == nil {
+		return nil, errors.New("errorreporting: Client is required")
+	}
+	if cfg.Formatter == nil {
+		cfg.Formatter = &appengine.Formatter{StackTrace: true, ErrorReporting: true}
+	}
+	if err := checkFormatter(cfg.Formatter); err != nil {
+		return nil, err
+	}
+	if cfg.Levels == nil {
+		cfg.Levels = []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel}
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultEndpoint
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	h := &Hook{
+		cfg:   cfg,
+		queue: make(chan []byte, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go h.run()
+	return h, nil
+}
+
+// checkFormatter returns an error if f has options that make its output
+// something event can't parse: not a single JSON object, missing for some
+// entries, or with the fields it reads renamed.
+func checkFormatter(f *appengine.Formatter) error {
+	switch {
+	case f.DevMode:
+		return errors.New("errorreporting: Formatter with DevMode is not supported")
+	case f.MinSeverity != "":
+		return errors.New("errorreporting: Formatter with MinSeverity is not supported")
+	case len(f.FieldMap) > 0:
+		return errors.New("errorreporting: Formatter with FieldMap is not supported")
+	case f.KeyCasing != appengine.KeyCasingNone:
+		return errors.New("errorreporting: Formatter with KeyCasing is not supported")
+	case f.NonBlockingDetection:
+		return errors.New("errorreporting: Formatter with NonBlockingDetection is not supported")
+	}
+	return nil
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []log.Level {
+	return h.cfg.Levels
+}
+
+// Fire implements logrus.Hook. It returns an error if the event can't be
+// queued.
+func (h *Hook) Fire(entry *log.Entry) error {
+	event, err := h.event(entry)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return errors.New("errorreporting: hook is closed")
+	}
+	h.pending.Add(1)
+	select {
+	case h.queue <- event:
+		return nil
+	default:
+		h.pending.Done()
+		return errors.New("errorreporting: queue is full, event dropped")
+	}
+}
+
+// Flush waits until all queued events are sent or dropped.
+func (h *Hook) Flush() {
+	h.pending.Wait()
+}
+
+// Close flushes queued events and stops the background worker. Events fired
+// after Close are rejected.
+func (h *Hook) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.queue)
+	h.mu.Unlock()
+	<-h.done
+	return nil
+}
+
+func (h *Hook) run() {
+	defer close(h.done)
+	for event := range h.queue {
+		if err := h.send(event); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to report error event, %v\n", err)
+		}
+		h.pending.Done()
+	}
+}
+
+// send submits the event, retrying with exponential backoff on errors that
+// are likely to be transient.
+func (h *Hook) send(event []byte) error {
+	url := h.cfg.Endpoint + "/v1beta1/projects/" + h.cfg.ProjectID + "/events:report"
+	backoff := h.cfg.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		retry, err = h.post(url, event)
+		if err == nil || !retry || attempt >= h.cfg.MaxAttempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes a single API request and reports whether it's worth retrying
+// if it failed.
+func (h *Hook) post(url string, event []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequest("POST", url, bytes.NewReader(event))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+	err = fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// event converts the entry into ReportedErrorEvent, see
+// https://cloud.google.com/error-reporting/reference/rest/v1beta1/projects.events/report
+func (h *Hook) event(entry *log.Entry) ([]byte, error) {
+	formatted, err := h.cfg.Formatter.Format(appengine.CloneEntry(entry))
+	if err != nil {
+		return nil, err
+	}
+	var fields struct {
+		StackTrace     string                     `json:"stack_trace"`
+		ServiceContext *appengine.ServiceContext  `json:"serviceContext"`
+		Context        map[string]json.RawMessage `json:"context"`
+		HTTPRequest    *struct {
+			Method    string `json:"requestMethod"`
+			URL       string `json:"requestUrl"`
+			UserAgent string `json:"userAgent"`
+			Referer   string `json:"referer"`
+			Status    int    `json:"status"`
+			RemoteIP  string `json:"remoteIp"`
+		} `json:"httpRequest"`
+	}
+	if err := json.Unmarshal(formatted, &fields); err != nil {
+		return nil, err
+	}
+
+	event := map[string]interface{}{
+		"eventTime": entry.Time.UTC().Format(time.RFC3339Nano),
+		"message":   fields.StackTrace,
+	}
+	if fields.StackTrace == "" {
+		event["message"] = entry.Message
+	}
+	if fields.ServiceContext != nil {
+		event["serviceContext"] = fields.ServiceContext
+	} else {
+		// serviceContext.service is required by the API.
+		event["serviceContext"] = appengine.ServiceContext{Service: "default"}
+	}
+	ctx := map[string]interface{}{}
+	if l, ok := fields.Context["reportLocation"]; ok {
+		ctx["reportLocation"] = l
+	}
+	if r := fields.HTTPRequest; r != nil {
+		ctx["httpRequest"] = map[string]interface{}{
+			"method":             r.Method,
+			"url":                r.URL,
+			"userAgent":          r.UserAgent,
+			"referrer":           r.Referer,
+			"responseStatusCode": r.Status,
+			"remoteIp":           r.RemoteIP,
+		}
+	}
+	if len(ctx) > 0 {
+		event["context"] = ctx
+	}
+	return json.Marshal(event)
+}
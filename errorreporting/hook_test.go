@@ -0,0 +1,123 @@
+package errorreporting
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestHook(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]interface{}
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/v1beta1/projects/my-project/events:report" {
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		event := map[string]interface{}{}
+		if err := json.Unmarshal(b, &event); err != nil {
+			t.Errorf("Unable to unmarshal event: %v", err)
+		}
+		events = append(events, event)
+	}))
+	defer srv.Close()
+
+	hook, err := NewHook(Config{
+		ProjectID: "my-project",
+		Client:    srv.Client(),
+		Endpoint:  srv.URL,
+		Backoff:   time.Millisecond,
+		Formatter: &appengine.Formatter{
+			StackTrace:     true,
+			ServiceContext: &appengine.ServiceContext{Service: "api", Version: "v1"},
+		},
+	})
+	if err != nil {
+		t.Fatal("NewHook failed: ", err)
+	}
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	logger.AddHook(hook)
+
+	logger.Info("not reported")
+	logger.Error("wild walrus")
+	hook.Close()
+
+	if len(events) != 1 {
+		t.Fatalf("Got %d events, want 1: %v", len(events), events)
+	}
+	if attempts != 2 {
+		t.Errorf("Got %d attempts, want 2", attempts)
+	}
+	event := events[0]
+	if msg, _ := event["message"].(string); !strings.HasPrefix(msg, "wild walrus\n\ngoroutine ") {
+		t.Errorf("Unexpected message: %q", msg)
+	}
+	if msg, _ := event["message"].(string); strings.Contains(msg, "(*Hook)") {
+		t.Errorf("Stack trace includes frames of the hook: %q", msg)
+	}
+	if sc, _ := event["serviceContext"].(map[string]interface{}); sc["service"] != "api" || sc["version"] != "v1" {
+		t.Errorf("Unexpected serviceContext: %v", event["serviceContext"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, event["eventTime"].(string)); err != nil {
+		t.Errorf("Unexpected eventTime: %v", err)
+	}
+
+	if err := hook.Fire(log.NewEntry(logger)); err == nil {
+		t.Error("Fire succeeded after Close")
+	}
+}
+
+func TestHookPermanentError(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "bad event", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	hook, err := NewHook(Config{ProjectID: "p", Client: srv.Client(), Endpoint: srv.URL, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal("NewHook failed: ", err)
+	}
+	if err := hook.Fire(log.NewEntry(log.New())); err != nil {
+		t.Fatal("Fire failed: ", err)
+	}
+	hook.Flush()
+	hook.Close()
+
+	if attempts != 1 {
+		t.Errorf("Got %d attempts, want 1", attempts)
+	}
+}
+
+func TestNewHookUnsupportedFormatter(t *testing.T) {
+	for _, f := range []*appengine.Formatter{
+		{DevMode: true},
+		{MinSeverity: appengine.SeverityCritical},
+		{FieldMap: appengine.FieldMap{appengine.FieldKeyMessage: "msg"}},
+		{KeyCasing: appengine.KeyCasingSnake},
+		{NonBlockingDetection: true},
+	} {
+		if hook, err := NewHook(Config{ProjectID: "p", Client: http.DefaultClient, Formatter: f}); err == nil {
+			hook.Close()
+			t.Errorf("NewHook with Formatter %+v succeeded, want error", f)
+		}
+	}
+}
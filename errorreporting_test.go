@@ -0,0 +1,99 @@
+package appengine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestErrorReportingType(t *testing.T) {
+	tests := []struct {
+		formatter *Formatter
+		level     log.Level
+		want      bool
+	}{
+		{&Formatter{}, log.ErrorLevel, false},
+		{&Formatter{ErrorReporting: true}, log.ErrorLevel, true},
+		{&Formatter{ErrorReporting: true}, log.FatalLevel, true},
+		{&Formatter{ErrorReporting: true}, log.WarnLevel, false},
+		{&Formatter{ErrorReporting: true, ErrorReportingMinSeverity: SeverityWarning}, log.WarnLevel, true},
+		{&Formatter{ErrorReporting: true, ErrorReportingMinSeverity: SeverityCritical}, log.ErrorLevel, false},
+	}
+	for _, test := range tests {
+		e := log.WithField("a", 1)
+		e.Level = test.level
+		entry := formatToMap(t, test.formatter, e)
+		if _, got := entry["@type"]; got != test.want {
+			t.Errorf("%+v at %s: @type present = %v, want %v", test.formatter, test.level, got, test.want)
+		}
+		if got := entry["@type"]; test.want && got != reportedErrorEventType {
+			t.Errorf("@type = %v, want %s", got, reportedErrorEventType)
+		}
+	}
+}
+
+type stackError struct {
+	error
+	frames []runtime.Frame
+}
+
+func (e *stackError) StackFrames() []runtime.Frame { return e.frames }
+
+func (e *stackError) Unwrap() error { return e.error }
+
+func TestReportLocation(t *testing.T) {
+	log.SetReportCaller(true)
+	defer log.SetReportCaller(false)
+	formatter := &Formatter{TrimFilenamePrefix: "/src/", ErrorReporting: true}
+	caller := &runtime.Frame{Function: "main.handler", File: "/src/main.go", Line: 10}
+
+	e := log.WithField("a", 1)
+	e.Level = log.ErrorLevel
+	e.Caller = caller
+	entry := formatToMap(t, formatter, e)
+	want := map[string]interface{}{
+		"reportLocation": map[string]interface{}{
+			"functionName": "main.handler",
+			"filePath":     "main.go",
+			"lineNumber":   float64(10),
+		},
+	}
+	if !reflect.DeepEqual(entry["context"], want) {
+		t.Errorf("context = %v, want %v", entry["context"], want)
+	}
+
+	inner := &stackError{errors.New("wild walrus"), []runtime.Frame{{Function: "db.Query", File: "/src/db/db.go", Line: 42}}}
+	outer := &stackError{fmt.Errorf("query: %w", inner), []runtime.Frame{{Function: "main.load", File: "/src/main.go", Line: 20}}}
+	e = log.WithError(outer)
+	e.Level = log.ErrorLevel
+	e.Caller = caller
+	entry = formatToMap(t, formatter, e)
+	want = map[string]interface{}{
+		"reportLocation": map[string]interface{}{
+			"functionName": "db.Query",
+			"filePath":     "db/db.go",
+			"lineNumber":   float64(42),
+		},
+	}
+	if !reflect.DeepEqual(entry["context"], want) {
+		t.Errorf("context = %v, want %v", entry["context"], want)
+	}
+
+	e.Level = log.InfoLevel
+	entry = formatToMap(t, formatter, e)
+	if _, set := entry["context"]; set {
+		t.Errorf("context set on INFO entry: %v", entry["context"])
+	}
+
+	e = log.WithField("context", "user value")
+	e.Level = log.ErrorLevel
+	e.Caller = caller
+	entry = formatToMap(t, &Formatter{}, e)
+	if entry["context"] != "user value" {
+		t.Errorf("context = %v without ErrorReporting, want user value", entry["context"])
+	}
+}
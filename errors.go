@@ -0,0 +1,99 @@
+package appengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Error kinds returned by ClassifyError.
+const (
+	ErrorKindTimeout    = "timeout"
+	ErrorKindCanceled   = "canceled"
+	ErrorKindPermission = "permission"
+	ErrorKindValidation = "validation"
+	ErrorKindInternal   = "internal"
+)
+
+// ClassifyError returns a coarse kind of the error, based on the well-known
+// error values and types found in its chain. Errors that don't match
+// anything are classified as ErrorKindInternal.
+// Intended to be used as Formatter.ErrorKind.
+func ClassifyError(err error) string {
+	var (
+		timeout     interface{ Timeout() bool }
+		numErr      *strconv.NumError
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		invalidJSON *json.InvalidUnmarshalError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorKindCanceled
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &timeout) && timeout.Timeout():
+		return ErrorKindTimeout
+	case errors.Is(err, os.ErrPermission):
+		return ErrorKindPermission
+	case errors.As(err, &numErr),
+		errors.As(err, &syntaxErr),
+		errors.As(err, &typeErr),
+		errors.As(err, &invalidJSON):
+		return ErrorKindValidation
+	default:
+		return ErrorKindInternal
+	}
+}
+
+// errorValue returns the value to emit for an error field.
+func (f *Formatter) errorValue(err error) interface{} {
+	if f.ErrorSerializer != nil {
+		return f.ErrorSerializer(err)
+	}
+	if f.ErrorChain && errors.Unwrap(err) != nil {
+		return errorChain(err)
+	}
+	// Multi-errors, like the ones returned by errors.Join, are rendered as an
+	// array with an element per constituent error.
+	if multi, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := multi.Unwrap()
+		values := make([]interface{}, 0, len(errs))
+		for _, err := range errs {
+			if err != nil {
+				values = append(values, f.errorValue(err))
+			}
+		}
+		return values
+	}
+	// We know that the value is an error and .Error() will produce a
+	// human-readable string, but let's do one extra step and give it
+	// a chance to produce more structured value.
+	switch err := err.(type) {
+	case json.Marshaler:
+		return err
+	default:
+		return err.Error()
+	}
+}
+
+// chainLink describes a single error in the chain.
+type chainLink struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// errorChain returns description of all errors in the chain, starting from
+// the outermost one.
+func errorChain(err error) []chainLink {
+	var chain []chainLink
+	for ; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, chainLink{
+			Type:    fmt.Sprintf("%T", err),
+			Message: err.Error(),
+		})
+	}
+	return chain
+}
@@ -0,0 +1,115 @@
+package appengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestClassifyError(t *testing.T) {
+	_, numErr := strconv.Atoi("walrus")
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{context.Canceled, ErrorKindCanceled},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), ErrorKindTimeout},
+		{&os.PathError{Op: "open", Path: "/x", Err: os.ErrPermission}, ErrorKindPermission},
+		{numErr, ErrorKindValidation},
+		{json.Unmarshal([]byte("{"), &struct{}{}), ErrorKindValidation},
+		{errors.New("wild walrus"), ErrorKindInternal},
+	}
+	for _, test := range tests {
+		if got := ClassifyError(test.err); got != test.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", test.err, got, test.want)
+		}
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	formatter := &Formatter{ErrorKind: ClassifyError}
+
+	entry := formatToMap(t, formatter, log.WithError(context.Canceled))
+	if entry["error.kind"] != ErrorKindCanceled {
+		t.Errorf("error.kind not set, got %v", entry["error.kind"])
+	}
+
+	entry = formatToMap(t, formatter, log.WithField("omg", context.Canceled))
+	if _, set := entry["error.kind"]; set {
+		t.Errorf("error.kind set for an entry without error, got %v", entry["error.kind"])
+	}
+}
+
+func TestErrorChain(t *testing.T) {
+	formatter := &Formatter{ErrorChain: true}
+
+	base := errors.New("wild walrus")
+	err := fmt.Errorf("load config: %w", fmt.Errorf("open: %w", base))
+	entry := formatToMap(t, formatter, log.WithError(err))
+
+	want := []interface{}{
+		map[string]interface{}{"type": "*fmt.wrapError", "message": "load config: open: wild walrus"},
+		map[string]interface{}{"type": "*fmt.wrapError", "message": "open: wild walrus"},
+		map[string]interface{}{"type": "*errors.errorString", "message": "wild walrus"},
+	}
+	if !reflect.DeepEqual(entry[log.ErrorKey], want) {
+		t.Errorf("%s = %v, want %v", log.ErrorKey, entry[log.ErrorKey], want)
+	}
+
+	entry = formatToMap(t, formatter, log.WithError(errors.New("wild walrus")))
+	if entry[log.ErrorKey] != "wild walrus" {
+		t.Errorf("%s = %v, want plain string", log.ErrorKey, entry[log.ErrorKey])
+	}
+}
+
+// multiError mimics the error returned by errors.Join.
+type multiError []error
+
+func (e multiError) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+func (e multiError) Unwrap() []error { return e }
+
+func TestMultiError(t *testing.T) {
+	formatter := &Formatter{}
+
+	err := multiError{
+		errors.New("wild walrus"),
+		multiError{errors.New("angry albatross"), errors.New("sad seal")},
+	}
+	entry := formatToMap(t, formatter, log.WithError(err))
+
+	want := []interface{}{"wild walrus", []interface{}{"angry albatross", "sad seal"}}
+	if !reflect.DeepEqual(entry[log.ErrorKey], want) {
+		t.Errorf("%s = %v, want %v", log.ErrorKey, entry[log.ErrorKey], want)
+	}
+}
+
+func TestErrorSerializer(t *testing.T) {
+	formatter := &Formatter{
+		ErrorChain: true,
+		ErrorSerializer: func(err error) interface{} {
+			return map[string]string{"redacted": fmt.Sprintf("%T", err)}
+		},
+	}
+
+	entry := formatToMap(t, formatter, log.WithError(fmt.Errorf("secret: %w", errors.New("token"))))
+
+	want := map[string]interface{}{"redacted": "*fmt.wrapError"}
+	if !reflect.DeepEqual(entry[log.ErrorKey], want) {
+		t.Errorf("%s = %v, want %v", log.ErrorKey, entry[log.ErrorKey], want)
+	}
+}
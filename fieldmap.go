@@ -0,0 +1,38 @@
+package appengine
+
+// Keys of the fields set by Formatter that can be renamed with FieldMap.
+const (
+	FieldKeyMessage          = "message"
+	FieldKeySeverity         = "severity"
+	FieldKeyTimestamp        = "timestamp"
+	FieldKeyTime             = "time"
+	FieldKeyTimestampSeconds = "timestampSeconds"
+	FieldKeyTimestampNanos   = "timestampNanos"
+	FieldKeyLevel            = "level"
+	FieldKeySourceLocation   = sourceLocationKey
+)
+
+// fieldKeys lists all FieldKey* constants.
+var fieldKeys = []string{
+	FieldKeyMessage,
+	FieldKeySeverity,
+	FieldKeyTimestamp,
+	FieldKeyTime,
+	FieldKeyTimestampSeconds,
+	FieldKeyTimestampNanos,
+	FieldKeyLevel,
+	FieldKeySourceLocation,
+}
+
+// FieldMap maps default keys of the fields set by Formatter (FieldKey*
+// constants) to the keys they are emitted under. Note that Cloud Logging
+// only recognizes the default keys.
+type FieldMap map[string]string
+
+// resolve returns the key the field with the default key k is emitted under.
+func (m FieldMap) resolve(k string) string {
+	if v, ok := m[k]; ok && v != "" {
+		return v
+	}
+	return k
+}
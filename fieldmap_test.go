@@ -0,0 +1,49 @@
+package appengine
+
+import (
+	"runtime"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestFieldMap(t *testing.T) {
+	formatter := &Formatter{FieldMap: FieldMap{
+		FieldKeyMessage:        "msg",
+		FieldKeySeverity:       "lvl",
+		FieldKeyTimestamp:      "ts",
+		FieldKeyLevel:          "",
+		FieldKeySourceLocation: "caller",
+	}}
+	log.SetReportCaller(true)
+	defer log.SetReportCaller(false)
+
+	e := log.WithField("msg", "user field")
+	e.Message = "hello"
+	e.Level = log.WarnLevel
+	e.Caller = &runtime.Frame{Function: "main.handler", File: "/src/main.go", Line: 10}
+	entry := formatToMap(t, formatter, e)
+
+	for _, k := range []string{"message", "severity", "timestamp", sourceLocationKey} {
+		if _, set := entry[k]; set {
+			t.Errorf("%q set despite FieldMap: %v", k, entry[k])
+		}
+	}
+	want := map[string]interface{}{
+		"msg":        "hello",
+		"lvl":        "WARNING",
+		"level":      "warning",
+		"fields.msg": "user field",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%q = %v, want %v", k, entry[k], v)
+		}
+	}
+	if _, ok := entry["ts"].(map[string]interface{}); !ok {
+		t.Errorf("ts = %v, want timestamp object", entry["ts"])
+	}
+	if _, ok := entry["caller"].(map[string]interface{}); !ok {
+		t.Errorf("caller = %v, want source location", entry["caller"])
+	}
+}
@@ -0,0 +1,144 @@
+package appengine
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// lookupField returns the value of the entry field with the given key. Empty
+// key is never found.
+func lookupField(entry *log.Entry, key string) (interface{}, bool) {
+	if key == "" {
+		return nil, false
+	}
+	v, ok := entry.Data[key]
+	return v, ok
+}
+
+// findTypedField returns the entry field value for which match returns true.
+// Value stored under the given key is preferred, otherwise the one with the
+// lowest key is returned, so the choice doesn't depend on map iteration
+// order.
+func findTypedField(entry *log.Entry, key string, match func(interface{}) bool) (interface{}, bool) {
+	k, ok := typedFieldKey(entry, key, match)
+	if !ok {
+		return nil, false
+	}
+	return entry.Data[k], true
+}
+
+// typedFieldKey returns the key of the field findTypedField would return.
+func typedFieldKey(entry *log.Entry, key string, match func(interface{}) bool) (string, bool) {
+	if v, ok := entry.Data[key]; ok && match(v) {
+		return key, true
+	}
+	found, ok := "", false
+	for k, v := range entry.Data {
+		if match(v) && (!ok || k < found) {
+			found, ok = k, true
+		}
+	}
+	return found, ok
+}
+
+// withFormatterFields returns the entry with DefaultFields and fields
+// returned by FieldProvider merged in. Fields of the entry take precedence.
+// The original entry is not modified.
+func (f *Formatter) withFormatterFields(entry *log.Entry) *log.Entry {
+	var provided log.Fields
+	if f.FieldProvider != nil {
+		provided = f.FieldProvider(entry)
+	}
+	if len(f.DefaultFields) == 0 && len(provided) == 0 {
+		return entry
+	}
+	fields := make(log.Fields, len(f.DefaultFields)+len(provided)+len(entry.Data))
+	for k, v := range f.DefaultFields {
+		fields[k] = v
+	}
+	for k, v := range provided {
+		fields[k] = v
+	}
+	for k, v := range entry.Data {
+		fields[k] = v
+	}
+	e := *entry
+	e.Data = fields
+	return &e
+}
+
+// isSpecialField reports whether the entry field is consumed by the formatter
+// and should not be emitted as is. Of several HTTPRequest, Operation or
+// TraceContext values only the one chosen by findTypedField is consumed, the
+// others are emitted as ordinary fields.
+func (f *Formatter) isSpecialField(entry *log.Entry, k string, v interface{}) bool {
+	switch {
+	case isSeverityOverride(v), isCallSite(v), isNoTimestamp(v):
+		return true
+	case isHTTPRequest(v):
+		return isChosenField(entry, k, httpRequestKey, isHTTPRequest)
+	case isOperation(v):
+		return isChosenField(entry, k, operationKey, isOperation)
+	case isTraceContext(v):
+		return isChosenField(entry, k, traceContextKey, isTraceContext)
+	}
+	switch k {
+	case "":
+		return false
+	case f.TraceKey, f.SpanIDKey, f.TraceSampledKey, f.TraceProjectIDKey, f.InsertIDKey:
+		return true
+	case f.LabelsKey, labelsKey:
+		_, ok := v.(map[string]string)
+		return ok
+	}
+	return false
+}
+
+// isChosenField reports whether the entry field k is the one findTypedField
+// returns for key and match.
+func isChosenField(entry *log.Entry, k, key string, match func(interface{}) bool) bool {
+	chosen, _ := typedFieldKey(entry, key, match)
+	return k == chosen
+}
+
+// ClashPolicy selects what Formatter does with entry fields colliding with
+// the fields it sets, such as "message" or "severity".
+type ClashPolicy int
+
+// Supported clash policies.
+const (
+	// ClashRename emits colliding entry fields with ClashPrefix prepended to
+	// their keys.
+	ClashRename ClashPolicy = iota
+	// ClashDrop drops colliding entry fields.
+	ClashDrop
+	// ClashOverride emits colliding entry fields instead of the fields set
+	// by the formatter.
+	ClashOverride
+	// ClashError makes Format fail on entries with colliding fields.
+	ClashError
+)
+
+// defaultClashPrefix is used when Formatter.ClashPrefix is empty.
+const defaultClashPrefix = "fields."
+
+// clashKey returns the key the entry field k is emitted under. Keys that
+// collide with the fields set by the formatter are prefixed with
+// ClashPrefix, as many times as needed to collide with neither those nor
+// other entry fields.
+func (f *Formatter) clashKey(data log.Fields, entry *log.Entry, k string) string {
+	if _, set := data[k]; !set {
+		return k
+	}
+	prefix := f.ClashPrefix
+	if prefix == "" {
+		prefix = defaultClashPrefix
+	}
+	for {
+		k = prefix + k
+		_, set := data[k]
+		_, other := entry.Data[k]
+		if !set && !other {
+			return k
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package appengine
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestDefaultFields(t *testing.T) {
+	formatter := &Formatter{DefaultFields: log.Fields{"region": "eu", "binary": "api", "tenant": "default"}}
+	ctx := PushField(context.Background(), "tenant", "acme")
+	e := log.WithContext(ctx).WithField("region", "us")
+	entry := formatToMap(t, formatter, e)
+	want := map[string]interface{}{"region": "us", "binary": "api", "tenant": "acme"}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+	if _, set := e.Data["binary"]; set {
+		t.Error("DefaultFields added to the original entry")
+	}
+}
+
+func TestFieldProvider(t *testing.T) {
+	calls := 0
+	formatter := &Formatter{
+		DefaultFields: log.Fields{"flag": "off", "region": "eu"},
+		FieldProvider: func(e *log.Entry) log.Fields {
+			calls++
+			return log.Fields{"flag": "on", "call": calls, "msg_len": len(e.Message)}
+		},
+	}
+	e := log.WithField("call", "entry")
+	e.Message = "hello"
+	entry := formatToMap(t, formatter, e)
+	want := map[string]interface{}{"flag": "on", "region": "eu", "call": "entry", "msg_len": float64(5)}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+
+	formatToMap(t, formatter, e)
+	if calls != 2 {
+		t.Errorf("FieldProvider called %d times, want 2", calls)
+	}
+}
+
+func TestMultipleTypedFields(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		e := log.WithFields(log.Fields{
+			"b":          &HTTPRequest{Method: "POST"},
+			"a":          &HTTPRequest{Method: "GET"},
+			"op2":        &Operation{ID: "2"},
+			"op1":        &Operation{ID: "1"},
+			operationKey: &Operation{ID: "3"},
+		})
+		entry := formatToMap(t, &Formatter{}, e)
+
+		if r, _ := entry[httpRequestKey].(map[string]interface{}); r["requestMethod"] != "GET" {
+			t.Fatalf("%s = %v, want the one from the lowest key", httpRequestKey, entry[httpRequestKey])
+		}
+		if r, _ := entry["b"].(map[string]interface{}); r["requestMethod"] != "POST" {
+			t.Fatalf("b = %v, want the other HTTPRequest emitted as is", entry["b"])
+		}
+		if op, _ := entry[operationKey].(map[string]interface{}); op["id"] != "3" {
+			t.Fatalf("%s = %v, want the one under the canonical key", operationKey, entry[operationKey])
+		}
+		for _, k := range []string{"op1", "op2"} {
+			if _, ok := entry[k].(map[string]interface{}); !ok {
+				t.Fatalf("%s = %v, want the other Operation emitted as is", k, entry[k])
+			}
+		}
+	}
+}
@@ -2,11 +2,15 @@ package appengine
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"path"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -15,10 +19,43 @@ import (
 // https://cloud.google.com/logging/docs/agent/configuration#special-fields as
 // closely as possible.
 // Forked from logrus JSONFormatter.
+//
+// Formatter is safe for concurrent use. Values derived from the environment
+// (see ProjectID and ServiceContext) are looked up once, on first use or by
+// Init. Configuration must not be changed after the Formatter is first used.
 type Formatter struct {
 	// DisableTimestamp allows disabling automatic timestamps in output
 	DisableTimestamp bool
 
+	// ClashPrefix is prepended to the keys of entry fields colliding with
+	// the fields set by the formatter, such as "message" or "severity".
+	// Defaults to "fields.".
+	ClashPrefix string
+
+	// ClashPolicy selects what happens with entry fields colliding with the
+	// fields set by the formatter. Defaults to ClashRename, which prepends
+	// ClashPrefix to their keys.
+	ClashPolicy ClashPolicy
+
+	// DisableLevelField drops "level" field duplicating "severity" with the
+	// logrus level name, to save log volume.
+	DisableLevelField bool
+
+	// TimestampPrecision, if positive, truncates timestamps to a multiple
+	// of it, e.g. time.Microsecond for backends that reject nanosecond
+	// precision. Timestamps are emitted in UTC, independent of time zone and
+	// locale.
+	TimestampPrecision time.Duration
+
+	// Now, if set, is used instead of the time recorded by logrus for
+	// timestamps and everything derived from them, so tests and replay
+	// tools can produce deterministic output.
+	Now func() time.Time
+
+	// TimestampFormat selects how timestamps are emitted. Defaults to
+	// "timestamp" object with seconds and nanoseconds since Unix epoch.
+	TimestampFormat TimestampFormat
+
 	// CallerPrettyfier can be set by the user to modify the content
 	// of the function and file keys in the json data when ReportCaller is
 	// activated. If any of the returned value is the empty string the
@@ -32,77 +69,473 @@ type Formatter struct {
 	// before invoking CallerPrettyfier.
 	TrimFilenamePrefix string
 
+	// SourceLineAsString emits "line" of the source location as a string,
+	// the form of int64 values in the JSON mapping of LogEntrySourceLocation,
+	// for consumers that strictly follow it.
+	SourceLineAsString bool
+
+	// CallerSkip is the number of stack frames above the logrus caller to
+	// skip when reporting the source location, e.g. 1 for a logging helper
+	// function called from the code the location should point at.
+	CallerSkip int
+
+	// CallerSkipPackages lists import paths of packages, frames of which are
+	// skipped when reporting the source location, so wrapper logging
+	// packages don't report themselves. Paths ending with "/..." match all
+	// subpackages too. Skipping frames only works if the entry is formatted
+	// on the goroutine that logged it, which is the case with logrus.
+	CallerSkipPackages []string
+
+	// KeepModulePath disables trimming of the main module path (taken from
+	// the build info) from file names when TrimFilenamePrefix is not set.
+	// The trimming makes file names relative to the repository root for
+	// binaries built with -trimpath. SourceFileLocation is not needed then.
+	KeepModulePath bool
+
+	// DevMode renders entries as colorized single-line text for reading in
+	// a terminal during local development, instead of JSON. The same fields
+	// are included, so code paths don't change between development and
+	// production.
+	DevMode bool
+
 	// PrettyPrint will indent all json logs
 	PrettyPrint bool
+
+	// PrettyPrintSeverities enables indentation only for entries with the
+	// listed severities, e.g. to make CRITICAL entries stand out during local
+	// development. Has no effect if PrettyPrint is set.
+	PrettyPrintSeverities map[Severity]bool
+
+	// FieldOrder lists keys that are written at the beginning of the JSON
+	// object, in the given order. The rest of the keys follow in sorted
+	// order. See HighSignalFieldOrder for a sensible choice.
+	FieldOrder []string
+
+	// SummaryConformance makes entries render well in the summary line of
+	// Logs Explorer: severities not recognized by Cloud Logging (e.g. set
+	// with WithSeverity) are mapped to the closest recognized one or
+	// DEFAULT, and empty messages, which make Logs Explorer show the JSON
+	// payload instead, are replaced. See SummaryFormatter.
+	SummaryConformance bool
+
+	// SeverityMap overrides the default severities of logrus levels, e.g.
+	// to map TraceLevel to DEFAULT or WarnLevel to NOTICE. Levels not in
+	// the map keep their default severity.
+	SeverityMap map[log.Level]Severity
+
+	// MinSeverity drops all entries with severity below the given one,
+	// regardless of the logger level. Format returns empty output for such
+	// entries. Empty value disables filtering.
+	MinSeverity Severity
+
+	// ErrorHint, if set, is called with the error attached to the entry via
+	// logrus.WithError. It can return a severity to be used instead of the
+	// one derived from the entry level (e.g. to log "not found" errors as
+	// WARNING), and a value for the "code" field. Empty severity and nil
+	// code leave the entry unchanged. "code" field set explicitly on the
+	// entry takes precedence.
+	ErrorHint func(err error) (severity Severity, code interface{})
+
+	// ErrorKind, if set, is called with the error attached to the entry via
+	// logrus.WithError, and a non-empty result is emitted as "error.kind"
+	// field. See ClassifyError for a ready to use implementation.
+	ErrorKind func(err error) string
+
+	// TraceKey is the name of the entry field holding trace ID. If set, the
+	// value of that field is emitted under "logging.googleapis.com/trace"
+	// special key instead, which allows Cloud Logging to correlate the entry
+	// with the request trace.
+	TraceKey string
+
+	// ProjectID is the Google Cloud project ID used to expand raw trace IDs
+	// into "projects/<ProjectID>/traces/<trace ID>" resource names expected
+	// by Cloud Logging. Trace values that already start with "projects/" are
+	// left as is. If empty, DetectProjectID is used. It may query the
+	// metadata server, which Format waits for unless NonBlockingDetection is
+	// set. Formatters created with New or NewFromEnv start detection right
+	// away, call Init to detect it before logging otherwise.
+	ProjectID string
+
+	// TraceProjectID, if set, is used instead of ProjectID to qualify trace
+	// IDs, for setups where traces are collected in a project different
+	// from the one the program is running in.
+	TraceProjectID string
+
+	// TraceProjectIDKey is the name of the entry field holding project ID to
+	// qualify the trace ID of that particular entry with. Takes precedence
+	// over TraceProjectID and ProjectID.
+	TraceProjectIDKey string
+
+	// TraceURL enables "trace_url" field with a link to the trace in Cloud
+	// Console. The link is only emitted if the project of the trace is known.
+	TraceURL bool
+
+	// SpanIDKey is the name of the entry field holding span ID. If set, the
+	// value of that field is emitted under "logging.googleapis.com/spanId"
+	// special key instead.
+	SpanIDKey string
+
+	// TraceSampledKey is the name of the entry field holding a flag whether
+	// the trace is sampled. If set, the value of that field is emitted under
+	// "logging.googleapis.com/trace_sampled" special key instead. The value
+	// can be either a bool or a string accepted by strconv.ParseBool.
+	TraceSampledKey string
+
+	// TraceExtractor, if set, is used to get the trace context of entries
+	// that have a context (see logrus.Entry.WithContext) but no TraceContext
+	// field, e.g. to take it from the span stored in the context by a
+	// tracing library. Trace context stored with WithTraceContext or
+	// Middleware is used without it.
+	TraceExtractor func(ctx context.Context) (TraceContext, bool)
+
+	// LabelsKey is the name of the entry field holding map[string]string
+	// with labels. If set, the labels are emitted under
+	// "logging.googleapis.com/labels" special key instead. See also
+	// WithLabels.
+	LabelsKey string
+
+	// DefaultFields are added to every entry, e.g. environment, region or
+	// binary name. Fields set on the entry or pushed into its context take
+	// precedence.
+	DefaultFields log.Fields
+
+	// RedactKeys lists keys, matched case-insensitively, values of which
+	// are replaced with "[REDACTED]" in entry fields, including maps nested
+	// in them (map[string]interface{}, map[string]string, logrus.Fields and
+	// []interface{} of those). Values of other types, such as structs, are
+	// not inspected. Redaction also applies to messages generated with
+	// SummaryConformance. The entry itself is never modified.
+	RedactKeys []string
+
+	// FieldProvider, if set, is called for every entry being formatted, and
+	// the returned fields are added to it, for values that must be computed
+	// at log time, e.g. remaining deadline of the request. Fields set on the
+	// entry or pushed into its context take precedence, DefaultFields
+	// don't.
+	FieldProvider func(entry *log.Entry) log.Fields
+
+	// Labels are added to "logging.googleapis.com/labels" of every entry.
+	// Labels attached to the entry itself take precedence.
+	Labels map[string]string
+
+	// EnvironmentLabels enables labels describing the runtime environment,
+	// detected once from environment variables, see
+	// DetectEnvironmentLabels. Labels set explicitly take precedence. It
+	// also enables "serviceContext" detected from the environment on errors,
+	// as if ErrorReporting was set, see ServiceContext.
+	EnvironmentLabels bool
+
+	// ResourceDetectors, if set, are tried in order once to detect the
+	// resource the program is running on, e.g. DefaultResourceDetectors.
+	// Labels of the detected resource are used instead of EnvironmentLabels,
+	// and its ServiceContext is used if ServiceContext is not set. Some
+	// detectors, e.g. GKEDetector, may query the metadata server, call Init
+	// to run them before logging starts.
+	ResourceDetectors []ResourceDetector
+
+	// ContextLabels, if set, is called with the context attached to the
+	// entry (see logrus.Entry.WithContext), and the returned labels are
+	// added to the entry, e.g. to attribute every request-scoped entry to
+	// the authenticated tenant. Labels attached to the entry itself take
+	// precedence, static Labels don't.
+	ContextLabels func(ctx context.Context) map[string]string
+
+	// InsertIDKey is the name of the entry field holding a unique entry ID.
+	// If set, the value of that field is emitted under
+	// "logging.googleapis.com/insertId" special key instead, which allows
+	// Cloud Logging to deduplicate entries delivered more than once.
+	InsertIDKey string
+
+	// GenerateInsertID enables emitting a random insertId for entries that
+	// don't have one set via InsertIDKey.
+	GenerateInsertID bool
+
+	// ErrorReporting marks entries with severity ERROR or above with "@type"
+	// of ReportedErrorEvent, so they are picked up by Error Reporting even
+	// if the message doesn't contain a stack trace, and adds
+	// "serviceContext" and "context.reportLocation" fields to them. "@type"
+	// field set explicitly on the entry takes precedence.
+	ErrorReporting bool
+
+	// ErrorReportingMinSeverity overrides the minimum severity of entries
+	// marked for Error Reporting. Defaults to ERROR.
+	ErrorReportingMinSeverity Severity
+
+	// ServiceContext is emitted as "serviceContext" field on entries with
+	// severity ERROR or above (see ErrorReportingMinSeverity). If nil and
+	// ErrorReporting or EnvironmentLabels is set, the detected one is used
+	// (see ResourceDetectors and DetectServiceContext).
+	ServiceContext *ServiceContext
+
+	// StackTrace enables "stack_trace" field on entries with severity ERROR
+	// or above (see ErrorReportingMinSeverity), in the format Error
+	// Reporting can parse. The stack is taken from the attached error, if it
+	// implements StackFramer, or captured at the time of formatting.
+	// "stack_trace" field set explicitly on the entry takes precedence.
+	StackTrace bool
+
+	// ErrorChain enables rendering of wrapped errors as an array of
+	// {"type", "message"} objects, one per error in the chain starting from
+	// the outermost one, instead of a single string.
+	ErrorChain bool
+
+	// ErrorSerializer, if set, is used to render error values of entry
+	// fields, e.g. to redact messages or emit typed payloads. It overrides
+	// the default rendering, including ErrorChain.
+	ErrorSerializer func(err error) interface{}
+
+	// SchemaVersion, if set, is added to the labels of every entry as
+	// "log_schema_version", so saved queries can tell apart entries
+	// produced with different output layouts.
+	SchemaVersion string
+
+	// KeyAliases maps output keys to additional keys their values are
+	// duplicated under. Intended for transition periods when fleets with
+	// mixed binary versions must keep saved queries working while the output
+	// layout changes. Aliases never override other keys.
+	KeyAliases map[string]string
+
+	// KeyCasing transforms top-level output keys, e.g. for external
+	// consumers requiring snake_case keys. Keys reserved by Cloud Logging,
+	// such as "logging.googleapis.com/*" and "httpRequest", are never
+	// renamed. FieldOrder and KeyAliases refer to the transformed keys.
+	KeyCasing KeyCasing
+
+	// FieldMap renames keys of the fields set by the formatter, such as
+	// "message", "severity" and "timestamp" (see FieldKey* constants), for
+	// pipelines that expect different key names. KeyCasing, FieldOrder and
+	// KeyAliases apply to the renamed keys.
+	FieldMap FieldMap
+
+	// NonBlockingDetection makes Format never wait for project ID
+	// detection, which may involve querying the metadata server. Detection
+	// runs in the background instead, trace IDs are left unqualified until
+	// it succeeds, and failures are retried at most once a minute. The first
+	// failure is reported once with a WARNING entry written before the entry
+	// being formatted. Init still detects project ID synchronously.
+	NonBlockingDetection bool
+
+	// Interner, if set, provides pre-escaped encodings of frequently
+	// repeated keys and string values, e.g. for high-volume access logs.
+	Interner *Interner
+
+	// Signer, if set, is used to sign every entry. The signature is
+	// computed over the entry without the signature, canonicalized as
+	// compact JSON with sorted keys, and stored base64-encoded in
+	// "signature" field.
+	Signer Signer
+
+	// WindowStats enables "stats" field on entries with severity ERROR or
+	// above, holding the numbers of all entries and of ERROR or more severe
+	// entries formatted during the last minute, to tell a spike from a
+	// one-off error.
+	WindowStats bool
+
+	// Process, if set, is emitted as "process" field, e.g.
+	// CurrentProcess(). If ProcessInterval is positive, the field is only
+	// added to one entry per interval.
+	Process         *Process
+	ProcessInterval time.Duration
+
+	// State of the background project ID detection, guarded by projectMu.
+	projectMu        sync.Mutex
+	bgProjectID      string
+	projectDetecting bool
+	projectRetryAt   time.Time
+	projectWarning   error
+	projectWarned    bool
+
+	serviceContextOnce     sync.Once
+	detectedServiceContext *ServiceContext
+
+	environmentOnce     sync.Once
+	detectedEnvironment map[string]string
+
+	resourceOnce     sync.Once
+	detectedResource *Resource
+
+	processMu   sync.Mutex
+	processLast time.Time
+
+	stats windowStats
 }
 
-func stackdriverLevel(l log.Level) string {
+func stackdriverLevel(l log.Level) Severity {
 	switch l {
 	case log.PanicLevel, log.FatalLevel:
-		return "CRITICAL"
+		return SeverityCritical
 	case log.ErrorLevel:
-		return "ERROR"
+		return SeverityError
 	case log.WarnLevel:
-		return "WARNING"
+		return SeverityWarning
 	case log.InfoLevel:
-		return "INFO"
+		return SeverityInfo
 	case log.DebugLevel, log.TraceLevel:
-		return "DEBUG"
+		return SeverityDebug
 	default:
-		return "DEFAULT"
+		return SeverityDefault
 	}
 }
 
 // Format renders a single log entry
 func (f *Formatter) Format(entry *log.Entry) ([]byte, error) {
+	entry = f.withFormatterFields(withContextFields(entry))
+	if f.Now != nil {
+		e := *entry
+		e.Time = f.Now()
+		entry = &e
+	}
+	severity, ok := f.SeverityMap[entry.Level]
+	if !ok {
+		severity = stackdriverLevel(entry.Level)
+	}
+	if s, ok := findTypedField(entry, "severity", isSeverityOverride); ok {
+		severity = Severity(s.(severityOverride))
+	}
+	var code interface{}
+	var errorKind string
+	if err, ok := entry.Data[log.ErrorKey].(error); ok {
+		if f.ErrorHint != nil {
+			var s Severity
+			s, code = f.ErrorHint(err)
+			if s != "" {
+				severity = s
+			}
+		}
+		if f.ErrorKind != nil {
+			errorKind = f.ErrorKind(err)
+		}
+	}
+	if f.SummaryConformance {
+		severity = normalizeSeverity(severity)
+	}
+	if f.MinSeverity != "" && severity.rank() < f.MinSeverity.rank() {
+		return nil, nil
+	}
+	if rl := requestLogFrom(entry.Context); rl != nil {
+		rl.record(severity)
+	}
+
 	data := make(log.Fields, len(entry.Data)+4)
 
-	if !f.DisableTimestamp {
-		data["timestamp"] = map[string]interface{}{
-			"seconds": entry.Time.Unix(),
-			"nanos":   entry.Time.Nanosecond(),
-		}
+	if f.hasTimestamp(entry) {
+		f.addTimestamp(data, entry.Time)
+	}
+	message := entry.Message
+	if f.SummaryConformance && strings.TrimSpace(message) == "" {
+		message = f.summaryMessage(entry, severity)
+	}
+	data[f.FieldMap.resolve(FieldKeyMessage)] = message
+	data[f.FieldMap.resolve(FieldKeySeverity)] = severity
+	if !f.DisableLevelField {
+		data[f.FieldMap.resolve(FieldKeyLevel)] = entry.Level.String()
+	}
+	if _, set := entry.Data["code"]; code != nil && !set {
+		data["code"] = code
+	}
+	if _, set := entry.Data["error.kind"]; errorKind != "" && !set {
+		data["error.kind"] = errorKind
 	}
-	data["message"] = entry.Message
-	data["severity"] = stackdriverLevel(entry.Level)
-	data["level"] = entry.Level.String()
-	if entry.HasCaller() {
+	if caller := f.entryCaller(entry); caller != nil {
 		l := map[string]interface{}{}
-		funcVal := entry.Caller.Function
-		fileVal := entry.Caller.File
-		fileVal = strings.TrimPrefix(fileVal, f.TrimFilenamePrefix)
-		if f.CallerPrettyfier != nil {
-			funcVal, fileVal = f.CallerPrettyfier(entry.Caller)
-		}
+		funcVal, fileVal := f.frameLocation(caller)
 		if funcVal != "" {
 			l["function"] = funcVal
 		}
 		if fileVal != "" {
 			l["file"] = fileVal
-			l["line"] = entry.Caller.Line
+			if f.SourceLineAsString {
+				l["line"] = strconv.Itoa(caller.Line)
+			} else {
+				l["line"] = caller.Line
+			}
+		}
+		data[f.FieldMap.resolve(FieldKeySourceLocation)] = l
+	}
+	f.addTraceFields(data, entry)
+	if labels := f.entryLabels(entry); labels != nil {
+		data[labelsKey] = labels
+	}
+	if f.isReportableError(severity) {
+		if _, set := entry.Data["@type"]; f.ErrorReporting && !set {
+			data["@type"] = reportedErrorEventType
+		}
+		if f.ErrorReporting || f.EnvironmentLabels || f.ServiceContext != nil {
+			if sc := f.serviceContext(); sc != nil {
+				data["serviceContext"] = sc
+			}
 		}
-		data["logging.googleapis.com/sourceLocation"] = l
+		if f.ErrorReporting {
+			if l := f.reportLocation(entry); l != nil {
+				data["context"] = map[string]interface{}{"reportLocation": l}
+			}
+		}
+		if _, set := entry.Data["stack_trace"]; f.StackTrace && !set {
+			data["stack_trace"] = stackTrace(entry)
+		}
+	}
+	if s := f.windowStats(entry.Time, severity); s != nil {
+		data[statsKey] = s
+	}
+	if p := f.processField(entry); p != nil {
+		data[processKey] = p
+	}
+	if id := f.insertID(entry); id != "" {
+		data[insertIDKey] = id
+	}
+	if req, ok := findTypedField(entry, httpRequestKey, isHTTPRequest); ok {
+		data[httpRequestKey] = req
+	}
+	if op, ok := findTypedField(entry, operationKey, isOperation); ok {
+		data[operationKey] = op
+	}
+
+	if f.Signer != nil {
+		// Reserved until the signature is computed, so that entry fields
+		// with the same key go through ClashPolicy.
+		data[signatureKey] = nil
 	}
 
 	for k, v := range entry.Data {
+		if f.isSpecialField(entry, k, v) {
+			continue
+		}
+		v = f.redactField(k, v)
 		if _, set := data[k]; set {
-			k = "fields." + k
+			switch f.ClashPolicy {
+			case ClashDrop:
+				continue
+			case ClashOverride:
+				if k == signatureKey && f.Signer != nil {
+					// The signature can't be overridden without
+					// defeating its purpose.
+					k = f.clashKey(data, entry, k)
+				}
+			case ClashError:
+				return nil, fmt.Errorf("failed to format entry, field %q collides with a reserved key", k)
+			default:
+				k = f.clashKey(data, entry, k)
+			}
 		}
 		switch v := v.(type) {
 		case error:
-			// We know that the value is an error and .Error() will produce a
-			// human-readable string, but let's do one extra step and give it
-			// a chance to produce more structured value.
-			switch v := v.(type) {
-			case json.Marshaler:
-				data[k] = v
-			default:
-				data[k] = v.Error()
-			}
+			data[k] = f.errorValue(v)
 		default:
 			data[k] = v
 		}
 	}
+	applyKeyCasing(data, f.KeyCasing)
+	for k, alias := range f.KeyAliases {
+		if v, ok := data[k]; ok {
+			if _, set := data[alias]; !set {
+				data[alias] = v
+			}
+		}
+	}
+
+	if f.Signer != nil {
+		delete(data, signatureKey)
+	}
 
 	var b *bytes.Buffer
 	if entry.Buffer != nil {
@@ -111,17 +544,79 @@ func (f *Formatter) Format(entry *log.Entry) ([]byte, error) {
 		b = &bytes.Buffer{}
 	}
 
-	encoder := json.NewEncoder(b)
-	if f.PrettyPrint {
-		encoder.SetIndent("", "  ")
+	if f.DevMode {
+		if err := f.formatDev(b, entry.Time, severity, data); err != nil {
+			return nil, fmt.Errorf("failed to render fields, %v", err)
+		}
+		return b.Bytes(), nil
 	}
-	if err := encoder.Encode(data); err != nil {
+
+	if f.Signer != nil {
+		sig, err := f.signature(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign entry, %v", err)
+		}
+		data[signatureKey] = sig
+	}
+
+	pretty := f.PrettyPrint || f.PrettyPrintSeverities[severity]
+	if err := encodeObject(b, data, f.FieldOrder, pretty, f.Interner); err != nil {
 		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
 	}
+	if !pretty {
+		if err := ensureSingleLine(b); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.detectionWarning(); err != nil {
+		w, err := f.Format(&log.Entry{
+			Logger:  entry.Logger,
+			Data:    log.Fields{log.ErrorKey: err},
+			Time:    entry.Time,
+			Level:   log.WarnLevel,
+			Message: "Unable to detect project ID, trace IDs are not qualified with it",
+		})
+		if err != nil {
+			return nil, err
+		}
+		return append(w, b.Bytes()...), nil
+	}
 
 	return b.Bytes(), nil
 }
 
+// ensureSingleLine makes sure that an encoded entry contains no line breaks
+// other than the trailing one, since logging agents treat every line as a
+// separate entry. encoding/json already escapes line breaks in strings and
+// compacts json.RawMessage values, so this is only a safety net and costs a
+// single scan of the buffer in the common case.
+func ensureSingleLine(b *bytes.Buffer) error {
+	out := bytes.TrimRight(b.Bytes(), "\r\n")
+	if bytes.IndexAny(out, "\r\n") < 0 {
+		return nil
+	}
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, out); err != nil {
+		return fmt.Errorf("failed to compact JSON, %v", err)
+	}
+	b.Reset()
+	compacted.WriteByte('\n')
+	_, err := compacted.WriteTo(b)
+	return err
+}
+
+// frameLocation returns function and file names of the frame, as they should
+// be shown in the output.
+func (f *Formatter) frameLocation(frame *runtime.Frame) (function string, file string) {
+	if f.CallerPrettyfier != nil {
+		return f.CallerPrettyfier(frame)
+	}
+	if f.TrimFilenamePrefix == "" && !f.KeepModulePath {
+		return frame.Function, trimModulePath(frame.File)
+	}
+	return frame.Function, strings.TrimPrefix(frame.File, f.TrimFilenamePrefix)
+}
+
 // SourceFileLocation returns path to directory containing the source file from
 // where it was called. Returns an empty string on error.
 // Intended to be used like this:
@@ -1,8 +1,10 @@
 package appengine
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -111,6 +113,51 @@ func TestFieldClashWithLevel(t *testing.T) {
 	}
 }
 
+func TestClashPrefix(t *testing.T) {
+	e := log.WithFields(log.Fields{"level": "something", "user_level": "other"})
+	entry := formatToMap(t, &Formatter{ClashPrefix: "user_"}, e)
+	if entry["user_user_level"] != "something" {
+		t.Errorf("user_user_level = %v, want original level field", entry["user_user_level"])
+	}
+	if entry["user_level"] != "other" {
+		t.Errorf("user_level = %v, want original user_level field", entry["user_level"])
+	}
+
+	e = log.WithFields(log.Fields{"message": "something", "fields.message": "other"})
+	entry = formatToMap(t, &Formatter{}, e)
+	if entry["fields.fields.message"] != "something" || entry["fields.message"] != "other" {
+		t.Errorf("fields.fields.message = %v, fields.message = %v, want something and other", entry["fields.fields.message"], entry["fields.message"])
+	}
+}
+
+func TestClashPolicy(t *testing.T) {
+	newEntry := func() *log.Entry {
+		e := log.WithField("severity", "custom")
+		e.Level = log.InfoLevel
+		return e
+	}
+
+	entry := formatToMap(t, &Formatter{ClashPolicy: ClashDrop}, newEntry())
+	if entry["severity"] != "INFO" {
+		t.Errorf("ClashDrop: severity = %v, want INFO", entry["severity"])
+	}
+	if _, set := entry["fields.severity"]; set {
+		t.Errorf("ClashDrop: fields.severity = %v, want unset", entry["fields.severity"])
+	}
+
+	entry = formatToMap(t, &Formatter{ClashPolicy: ClashOverride}, newEntry())
+	if entry["severity"] != "custom" {
+		t.Errorf("ClashOverride: severity = %v, want custom", entry["severity"])
+	}
+
+	if _, err := (&Formatter{ClashPolicy: ClashError}).Format(newEntry()); err == nil {
+		t.Error("ClashError: Format succeeded, want error")
+	}
+	if _, err := (&Formatter{ClashPolicy: ClashError}).Format(log.WithField("a", 1)); err != nil {
+		t.Errorf("ClashError: Format failed without collisions: %v", err)
+	}
+}
+
 func TestJSONEntryEndsWithNewline(t *testing.T) {
 	formatter := &Formatter{}
 
@@ -188,6 +235,23 @@ func TestJSONDisableTimestamp(t *testing.T) {
 	}
 }
 
+func TestDisableLevelField(t *testing.T) {
+	e := log.WithField("a", 1)
+	e.Level = log.InfoLevel
+	entry := formatToMap(t, &Formatter{DisableLevelField: true}, e)
+	if _, set := entry["level"]; set {
+		t.Errorf("level = %v, want unset", entry["level"])
+	}
+	if entry["severity"] != "INFO" {
+		t.Errorf("severity = %v, want INFO", entry["severity"])
+	}
+
+	entry = formatToMap(t, &Formatter{DisableLevelField: true}, log.WithField("level", "custom"))
+	if entry["level"] != "custom" {
+		t.Errorf("level = %v, want user-set value", entry["level"])
+	}
+}
+
 func TestJSONEnableTimestamp(t *testing.T) {
 	formatter := &Formatter{}
 
@@ -200,3 +264,239 @@ func TestJSONEnableTimestamp(t *testing.T) {
 		t.Error("Timestamp not present", s)
 	}
 }
+
+func TestTimestamp(t *testing.T) {
+	utc := time.Date(2019, 3, 10, 7, 30, 0, 123456789, time.UTC)
+	for _, test := range []struct {
+		desc      string
+		time      time.Time
+		precision time.Duration
+		seconds   int64
+		nanos     int
+	}{
+		{"UTC", utc, 0, 1552203000, 123456789},
+		// Same instant on both sides of a DST transition.
+		{"EST", utc.In(time.FixedZone("EST", -5*3600)), 0, 1552203000, 123456789},
+		{"EDT", utc.In(time.FixedZone("EDT", -4*3600)), 0, 1552203000, 123456789},
+		{"monotonic", time.Now(), 0, 0, 0},
+		// Go normalizes leap seconds to the first second of the next minute.
+		{"leap second", time.Date(2016, 12, 31, 23, 59, 60, 0, time.UTC), 0, 1483228800, 0},
+		// Nanos are never negative, as required by google.protobuf.Timestamp.
+		{"before epoch", time.Unix(-1, 500), 0, -1, 500},
+		{"microseconds", utc, time.Microsecond, 1552203000, 123456000},
+		{"milliseconds before epoch", time.Unix(-1, 999999999), time.Millisecond, -1, 999000000},
+	} {
+		if test.desc == "monotonic" {
+			test.seconds = test.time.Unix()
+			test.nanos = test.time.Nanosecond()
+		}
+		e := log.WithTime(test.time)
+		entry := formatToMap(t, &Formatter{TimestampPrecision: test.precision}, e)
+		want := map[string]interface{}{"seconds": float64(test.seconds), "nanos": float64(test.nanos)}
+		ts, _ := entry["timestamp"].(map[string]interface{})
+		if ts["seconds"] != want["seconds"] || ts["nanos"] != want["nanos"] {
+			t.Errorf("%s: timestamp = %v, want %v", test.desc, entry["timestamp"], want)
+		}
+	}
+}
+
+func TestNow(t *testing.T) {
+	now := time.Unix(1552203000, 123456789)
+	formatter := &Formatter{Now: func() time.Time { return now }}
+	e := log.WithTime(time.Now())
+	entry := formatToMap(t, formatter, e)
+	want := map[string]interface{}{"seconds": float64(1552203000), "nanos": float64(123456789)}
+	if !reflect.DeepEqual(entry["timestamp"], want) {
+		t.Errorf("timestamp = %v, want %v", entry["timestamp"], want)
+	}
+	if !e.Time.After(now) {
+		t.Errorf("entry time modified: %v", e.Time)
+	}
+}
+
+func TestTimestampFormatRFC3339(t *testing.T) {
+	e := log.WithTime(time.Date(2019, 3, 10, 2, 30, 0, 123456789, time.FixedZone("EST", -5*3600)))
+	entry := formatToMap(t, &Formatter{TimestampFormat: TimestampFormatRFC3339}, e)
+	if want := "2019-03-10T07:30:00.123456789Z"; entry["time"] != want {
+		t.Errorf("time = %v, want %s", entry["time"], want)
+	}
+	if _, set := entry["timestamp"]; set {
+		t.Errorf("timestamp = %v, want unset", entry["timestamp"])
+	}
+}
+
+func TestTimestampFormatSplit(t *testing.T) {
+	e := log.WithTime(time.Unix(1552203000, 123456789))
+	entry := formatToMap(t, &Formatter{TimestampFormat: TimestampFormatSplit}, e)
+	if entry["timestampSeconds"] != float64(1552203000) || entry["timestampNanos"] != float64(123456789) {
+		t.Errorf("timestampSeconds = %v, timestampNanos = %v, want 1552203000 and 123456789", entry["timestampSeconds"], entry["timestampNanos"])
+	}
+	if _, set := entry["timestamp"]; set {
+		t.Errorf("timestamp = %v, want unset", entry["timestamp"])
+	}
+}
+
+func TestMinSeverity(t *testing.T) {
+	formatter := &Formatter{MinSeverity: SeverityWarning}
+
+	e := log.WithField("level", "something")
+	e.Level = log.InfoLevel
+	b, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+	if len(b) != 0 {
+		t.Errorf("INFO entry was not dropped: %s", b)
+	}
+
+	e.Level = log.ErrorLevel
+	b, err = formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+	if len(b) == 0 {
+		t.Error("ERROR entry was dropped")
+	}
+}
+
+func TestErrorHint(t *testing.T) {
+	notFound := errors.New("not found")
+	formatter := &Formatter{
+		ErrorHint: func(err error) (Severity, interface{}) {
+			if err == notFound {
+				return SeverityWarning, 404
+			}
+			return "", nil
+		},
+	}
+
+	e := log.WithError(notFound)
+	e.Level = log.ErrorLevel
+	b, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+
+	entry := make(map[string]interface{})
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+
+	if entry["severity"] != "WARNING" {
+		t.Errorf("severity not adjusted, got %v", entry["severity"])
+	}
+	if entry["code"] != float64(404) {
+		t.Errorf("code not set, got %v", entry["code"])
+	}
+
+	e = log.WithError(errors.New("wild walrus"))
+	e.Level = log.ErrorLevel
+	b, err = formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+
+	entry = make(map[string]interface{})
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+
+	if entry["severity"] != "ERROR" {
+		t.Errorf("severity changed, got %v", entry["severity"])
+	}
+	if _, set := entry["code"]; set {
+		t.Errorf("unexpected code field: %v", entry["code"])
+	}
+}
+
+func TestSingleLine(t *testing.T) {
+	formatter := &Formatter{}
+
+	e := log.WithField("raw", json.RawMessage("{\n\"a\":\n1\n}"))
+	e.Message = "multi\nline\r\nmessage"
+	b, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+
+	if n := strings.Count(string(b), "\n"); n != 1 || b[len(b)-1] != '\n' {
+		t.Errorf("Expected exactly one trailing newline, got %d: %q", n, b)
+	}
+	if strings.Contains(string(b), "\r") {
+		t.Errorf("Unexpected carriage return: %q", b)
+	}
+}
+
+func TestEnsureSingleLine(t *testing.T) {
+	b := bytes.NewBufferString("{\"a\":\n1}\n")
+	if err := ensureSingleLine(b); err != nil {
+		t.Fatal("ensureSingleLine failed: ", err)
+	}
+	if b.String() != "{\"a\":1}\n" {
+		t.Errorf("Unexpected output: %q", b.String())
+	}
+}
+
+func TestPrettyPrintSeverities(t *testing.T) {
+	formatter := &Formatter{
+		PrettyPrintSeverities: map[Severity]bool{SeverityCritical: true},
+	}
+
+	e := log.WithField("level", "something")
+	e.Level = log.PanicLevel
+	b, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+	if strings.Count(string(b), "\n") < 2 {
+		t.Errorf("CRITICAL entry was not indented: %s", b)
+	}
+
+	e.Level = log.InfoLevel
+	b, err = formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+	if strings.Count(string(b), "\n") != 1 {
+		t.Errorf("INFO entry was indented: %s", b)
+	}
+}
+
+func TestKeyAliases(t *testing.T) {
+	formatter := &Formatter{
+		KeyAliases: map[string]string{
+			"severity": "old_severity",
+			"user":     "user_id",
+			"message":  "msg",
+			"missing":  "whatever",
+		},
+	}
+
+	e := log.WithFields(log.Fields{"user": "walrus", "msg": "taken"})
+	e.Message = "hello"
+	b, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+
+	entry := make(map[string]interface{})
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+
+	if entry["old_severity"] != "CRITICAL" || entry["severity"] != "CRITICAL" {
+		t.Errorf("severity not duplicated: %v", entry)
+	}
+	if entry["user_id"] != "walrus" || entry["user"] != "walrus" {
+		t.Errorf("user not duplicated: %v", entry)
+	}
+	if entry["msg"] != "taken" {
+		t.Errorf("Alias overrode existing key: %v", entry)
+	}
+	if _, set := entry["whatever"]; set {
+		t.Errorf("Alias of a missing key set: %v", entry)
+	}
+}
@@ -0,0 +1,198 @@
+// Package grpclogging provides gRPC interceptors correlating logs of RPCs
+// with their traces:
+//
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(grpclogging.UnaryServerInterceptor(logger)),
+//		grpc.StreamInterceptor(grpclogging.StreamServerInterceptor(logger)),
+//	)
+//
+// Server interceptors extract the trace context from X-Cloud-Trace-Context
+// or W3C traceparent metadata of incoming calls and store it in the call
+// context with appengine.WithTraceContext. Handlers log with the per-RPC
+// entry returned by Entry. Client interceptors propagate the trace context
+// of the call context in traceparent metadata. Both emit a summary entry
+// per RPC with method, status code and latency.
+package grpclogging
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Keys of the fields set on per-RPC and summary entries.
+const (
+	MethodKey  = "grpc.method"
+	CodeKey    = "grpc.code"
+	LatencyKey = "grpc.latency"
+)
+
+type entryKey struct{}
+
+// Entry returns the per-RPC entry stored in ctx by server interceptors, or
+// an entry of the standard logger carrying ctx if there's none.
+func Entry(ctx context.Context) *logrus.Entry {
+	if e, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+		return e
+	}
+	return logrus.WithContext(ctx)
+}
+
+// UnaryServerInterceptor returns an interceptor for unary RPCs logging with
+// logger.
+func UnaryServerInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = serverContext(ctx, logger, info.FullMethod)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logSummary(Entry(ctx), "Finished unary call", start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor for streaming RPCs logging
+// with logger.
+func StreamServerInterceptor(logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := serverContext(ss.Context(), logger, info.FullMethod)
+		start := time.Now()
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		logSummary(Entry(ctx), "Finished streaming call", start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor for unary RPCs logging with
+// logger.
+func UnaryClientInterceptor(logger *logrus.Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+		logSummary(logger.WithContext(ctx).WithField(MethodKey, method), "Finished unary client call", start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor for streaming RPCs logging
+// with logger. The summary entry is emitted once when the stream ends, i.e.
+// when receiving from it fails, or, for calls without server streaming,
+// when the response is received.
+func StreamClientInterceptor(logger *logrus.Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		entry := logger.WithContext(ctx).WithField(MethodKey, method)
+		cs, err := streamer(outgoingContext(ctx), desc, cc, method, opts...)
+		if err != nil {
+			logSummary(entry, "Finished streaming client call", start, err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, entry: entry, start: start, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// serverContext returns the call context with the trace context of the
+// call and the per-RPC entry stored.
+func serverContext(ctx context.Context, logger *logrus.Logger, method string) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if tc, ok := traceContext(md); ok {
+			ctx = appengine.WithTraceContext(ctx, tc)
+		}
+	}
+	return context.WithValue(ctx, entryKey{}, logger.WithContext(ctx).WithField(MethodKey, method))
+}
+
+func traceContext(md metadata.MD) (appengine.TraceContext, bool) {
+	for _, v := range md.Get("x-cloud-trace-context") {
+		if tc, ok := appengine.ParseCloudTraceContext(v); ok {
+			return tc, true
+		}
+	}
+	for _, v := range md.Get("traceparent") {
+		if tc, ok := appengine.ParseTraceparent(v); ok {
+			return tc, true
+		}
+	}
+	return appengine.TraceContext{}, false
+}
+
+// outgoingContext returns ctx with traceparent metadata set from the trace
+// context stored in ctx, if there's one.
+func outgoingContext(ctx context.Context) context.Context {
+	tc, ok := appengine.TraceContextFrom(ctx)
+	if !ok || len(tc.TraceID) != 32 || len(tc.SpanID) != 16 {
+		return ctx
+	}
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+	return metadata.AppendToOutgoingContext(ctx, "traceparent", "00-"+tc.TraceID+"-"+tc.SpanID+"-"+flags)
+}
+
+// logSummary logs the summary entry of an RPC, with severity depending on
+// its status code.
+func logSummary(entry *logrus.Entry, msg string, start time.Time, err error) {
+	code := status.Code(err)
+	entry = entry.WithFields(logrus.Fields{
+		CodeKey:    code.String(),
+		LatencyKey: time.Since(start).Seconds(),
+	})
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Log(codeLevel(code), msg)
+}
+
+// codeLevel returns the level to log RPCs finished with the code with. Codes
+// normally caused by the client are logged as warnings.
+func codeLevel(code codes.Code) logrus.Level {
+	switch code {
+	case codes.OK:
+		return logrus.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	entry         *logrus.Entry
+	start         time.Time
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		result := err
+		if err == io.EOF {
+			result = nil
+		}
+		s.once.Do(func() {
+			logSummary(s.entry, "Finished streaming client call", s.start, result)
+		})
+	}
+	return err
+}
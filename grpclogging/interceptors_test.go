@@ -0,0 +1,162 @@
+package grpclogging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func newLogger() (*logrus.Logger, *bytes.Buffer) {
+	b := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = b
+	logger.Formatter = &appengine.Formatter{ProjectID: "p"}
+	return logger, b
+}
+
+func decode(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	d := json.NewDecoder(b)
+	for d.More() {
+		entry := make(map[string]interface{})
+		if err := d.Decode(&entry); err != nil {
+			t.Fatal("Unable to unmarshal formatted entry: ", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	logger, b := newLogger()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Get"}
+
+	_, err := UnaryServerInterceptor(logger)(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		Entry(ctx).Info("handling")
+		return nil, status.Error(codes.NotFound, "no such walrus")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Got error %v, want the one returned by the handler", err)
+	}
+
+	entries := decode(t, b)
+	if len(entries) != 2 {
+		t.Fatalf("Got %d entries, want 2", len(entries))
+	}
+	for i, e := range entries {
+		if e["logging.googleapis.com/trace"] != "projects/p/traces/"+traceID || e[MethodKey] != "/pkg.Service/Get" {
+			t.Errorf("Entry #%d: got trace %v and method %v", i, e["logging.googleapis.com/trace"], e[MethodKey])
+		}
+	}
+	summary := entries[1]
+	if summary["severity"] != "WARNING" || summary[CodeKey] != "NotFound" {
+		t.Errorf("Got summary severity %v and code %v, want WARNING and NotFound", summary["severity"], summary[CodeKey])
+	}
+	if _, ok := summary[LatencyKey].(float64); !ok {
+		t.Errorf("%s = %v, want a number", LatencyKey, summary[LatencyKey])
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	logger, b := newLogger()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-cloud-trace-context", traceID+"/1;o=1"))
+	info := &grpc.StreamServerInfo{FullMethod: "/pkg.Service/Watch"}
+
+	err := StreamServerInterceptor(logger)(nil, fakeServerStream{ctx: ctx}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		if tc, _ := appengine.TraceContextFrom(ss.Context()); tc.TraceID != traceID {
+			t.Errorf("Got trace context %+v in the stream context", tc)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Interceptor failed: ", err)
+	}
+
+	entries := decode(t, b)
+	if len(entries) != 1 || entries[0]["severity"] != "INFO" || entries[0][CodeKey] != "OK" {
+		t.Errorf("Got entries %v, want a single INFO summary", entries)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	logger, b := newLogger()
+	ctx := appengine.WithTraceContext(context.Background(), appengine.TraceContext{TraceID: traceID, SpanID: "00f067aa0ba902b7", Sampled: true})
+
+	var md metadata.MD
+	err := UnaryClientInterceptor(logger)(ctx, "/pkg.Service/Get", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ = metadata.FromOutgoingContext(ctx)
+			return status.Error(codes.Internal, "boom")
+		})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Got error %v, want the one returned by the invoker", err)
+	}
+
+	if got := md.Get("traceparent"); len(got) != 1 || got[0] != "00-"+traceID+"-00f067aa0ba902b7-01" {
+		t.Errorf("Got traceparent %v", got)
+	}
+	entries := decode(t, b)
+	if len(entries) != 1 || entries[0]["severity"] != "ERROR" || entries[0]["logging.googleapis.com/trace"] != "projects/p/traces/"+traceID {
+		t.Errorf("Got entries %v, want a single ERROR summary with the trace", entries)
+	}
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	recv []error
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	err := s.recv[0]
+	if len(s.recv) > 1 {
+		s.recv = s.recv[1:]
+	}
+	return err
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		desc *grpc.StreamDesc
+		recv []error
+	}{
+		{"client streaming", &grpc.StreamDesc{ClientStreams: true}, []error{nil, io.EOF}},
+		{"server streaming", &grpc.StreamDesc{ServerStreams: true}, []error{nil, nil, io.EOF}},
+	} {
+		logger, b := newLogger()
+		cs, err := StreamClientInterceptor(logger)(context.Background(), test.desc, nil, "/pkg.Service/Upload",
+			func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return &fakeClientStream{recv: test.recv}, nil
+			})
+		if err != nil {
+			t.Fatalf("%s: interceptor failed: %v", test.name, err)
+		}
+		for i := 0; i < len(test.recv)+1; i++ {
+			cs.RecvMsg(nil)
+		}
+		entries := decode(t, b)
+		if len(entries) != 1 || entries[0]["severity"] != "INFO" || entries[0][CodeKey] != "OK" {
+			t.Errorf("%s: got entries %v, want a single INFO summary", test.name, entries)
+		}
+	}
+}
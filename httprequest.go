@@ -0,0 +1,103 @@
+package appengine
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const httpRequestKey = "httpRequest"
+
+// HTTPRequest describes an HTTP request the entry is about. If a value of
+// this type, or a pointer to it, is attached to the entry under any key, it is
+// emitted under "httpRequest" special key, so Cloud Logging displays the
+// entry as a request log. See
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+type HTTPRequest struct {
+	Method       string
+	URL          string
+	RequestSize  int64
+	Status       int
+	ResponseSize int64
+	UserAgent    string
+	RemoteIP     string
+	ServerIP     string
+	Referer      string
+	Latency      time.Duration
+	Protocol     string
+
+	CacheLookup                    bool
+	CacheHit                       bool
+	CacheValidatedWithOriginServer bool
+	CacheFillBytes                 int64
+}
+
+// NewHTTPRequest returns HTTPRequest populated with the information
+// available from the incoming request. Status, response size and latency
+// are left for the caller to fill in.
+func NewHTTPRequest(r *http.Request) *HTTPRequest {
+	req := &HTTPRequest{
+		Method:    r.Method,
+		URL:       r.URL.String(),
+		UserAgent: r.UserAgent(),
+		RemoteIP:  r.RemoteAddr,
+		Referer:   r.Referer(),
+		Protocol:  r.Proto,
+	}
+	if r.ContentLength > 0 {
+		req.RequestSize = r.ContentLength
+	}
+	return req
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r HTTPRequest) MarshalJSON() ([]byte, error) {
+	type httpRequest struct {
+		RequestMethod                  string `json:"requestMethod,omitempty"`
+		RequestURL                     string `json:"requestUrl,omitempty"`
+		RequestSize                    int64  `json:"requestSize,string,omitempty"`
+		Status                         int    `json:"status,omitempty"`
+		ResponseSize                   int64  `json:"responseSize,string,omitempty"`
+		UserAgent                      string `json:"userAgent,omitempty"`
+		RemoteIP                       string `json:"remoteIp,omitempty"`
+		ServerIP                       string `json:"serverIp,omitempty"`
+		Referer                        string `json:"referer,omitempty"`
+		Latency                        string `json:"latency,omitempty"`
+		Protocol                       string `json:"protocol,omitempty"`
+		CacheLookup                    bool   `json:"cacheLookup,omitempty"`
+		CacheHit                       bool   `json:"cacheHit,omitempty"`
+		CacheValidatedWithOriginServer bool   `json:"cacheValidatedWithOriginServer,omitempty"`
+		CacheFillBytes                 int64  `json:"cacheFillBytes,string,omitempty"`
+	}
+	v := httpRequest{
+		RequestMethod:                  r.Method,
+		RequestURL:                     r.URL,
+		RequestSize:                    r.RequestSize,
+		Status:                         r.Status,
+		ResponseSize:                   r.ResponseSize,
+		UserAgent:                      r.UserAgent,
+		RemoteIP:                       r.RemoteIP,
+		ServerIP:                       r.ServerIP,
+		Referer:                        r.Referer,
+		Protocol:                       r.Protocol,
+		CacheLookup:                    r.CacheLookup,
+		CacheHit:                       r.CacheHit,
+		CacheValidatedWithOriginServer: r.CacheValidatedWithOriginServer,
+		CacheFillBytes:                 r.CacheFillBytes,
+	}
+	if r.Latency > 0 {
+		v.Latency = strconv.FormatFloat(r.Latency.Seconds(), 'f', -1, 64) + "s"
+	}
+	return json.Marshal(v)
+}
+
+func isHTTPRequest(v interface{}) bool {
+	switch v := v.(type) {
+	case HTTPRequest:
+		return true
+	case *HTTPRequest:
+		return v != nil
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package appengine
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestHTTPRequestMarshalJSON(t *testing.T) {
+	req := HTTPRequest{
+		Method:       "GET",
+		URL:          "/path?q=1",
+		Status:       200,
+		ResponseSize: 1234,
+		Latency:      1500 * time.Millisecond,
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal("Unable to marshal HTTPRequest: ", err)
+	}
+
+	want := `{"requestMethod":"GET","requestUrl":"/path?q=1","status":200,"responseSize":"1234","latency":"1.5s"}`
+	if string(b) != want {
+		t.Errorf("Got %s, want %s", b, want)
+	}
+}
+
+func TestHTTPRequestField(t *testing.T) {
+	formatter := &Formatter{}
+
+	r := httptest.NewRequest("POST", "http://example.com/x", nil)
+	r.Header.Set("User-Agent", "walrus/1.0")
+	req := NewHTTPRequest(r)
+	req.Status = 404
+
+	entry := formatToMap(t, formatter, log.WithField("req", req))
+
+	want := map[string]interface{}{
+		"requestMethod": "POST",
+		"requestUrl":    "http://example.com/x",
+		"status":        float64(404),
+		"userAgent":     "walrus/1.0",
+		"remoteIp":      "192.0.2.1:1234",
+		"protocol":      "HTTP/1.1",
+	}
+	if !reflect.DeepEqual(entry[httpRequestKey], want) {
+		t.Errorf("%s = %v, want %v", httpRequestKey, entry[httpRequestKey], want)
+	}
+	if _, set := entry["req"]; set {
+		t.Error("req field is not removed")
+	}
+}
@@ -0,0 +1,110 @@
+package appengine
+
+import (
+	"fmt"
+	"time"
+)
+
+// Init looks up values derived from the environment that are otherwise
+// resolved lazily on first use, so that the lookups (which may involve
+// querying the metadata server) don't delay logging while serving traffic.
+// Returns an error if project ID is not configured and can't be detected.
+// Calling Init is optional.
+func (f *Formatter) Init() error {
+	f.serviceContext()
+	f.environmentLabels()
+	if f.ProjectID != "" || f.TraceProjectID != "" {
+		return nil
+	}
+	if f.NonBlockingDetection {
+		id, err := detectedProjectID()
+		f.storeBackgroundProjectID(id, err)
+		if err != nil {
+			return fmt.Errorf("unable to detect project ID, %v", err)
+		}
+		return nil
+	}
+	if _, err := detectedProjectID(); err != nil {
+		return fmt.Errorf("unable to detect project ID, %v", err)
+	}
+	return nil
+}
+
+// startDetection starts project ID detection in the background, if project
+// ID is not configured. Format waits for it only if it's still running, and
+// only in the blocking mode.
+func (f *Formatter) startDetection() {
+	if f.ProjectID != "" || f.TraceProjectID != "" {
+		return
+	}
+	if f.NonBlockingDetection {
+		f.backgroundProjectID()
+		return
+	}
+	go detectedProjectID()
+}
+
+// backgroundProjectID returns project ID detected so far, starting detection
+// in the background if it's not known and not being detected already.
+func (f *Formatter) backgroundProjectID() string {
+	f.projectMu.Lock()
+	defer f.projectMu.Unlock()
+	if f.bgProjectID == "" && !f.projectDetecting && !time.Now().Before(f.projectRetryAt) {
+		f.projectDetecting = true
+		go func() {
+			f.storeBackgroundProjectID(detectedProjectID())
+		}()
+	}
+	return f.bgProjectID
+}
+
+// storeBackgroundProjectID records the result of project ID detection.
+// Last known project ID is never replaced with an empty one.
+func (f *Formatter) storeBackgroundProjectID(id string, err error) {
+	f.projectMu.Lock()
+	defer f.projectMu.Unlock()
+	f.projectDetecting = false
+	if err != nil || id == "" {
+		f.projectRetryAt = time.Now().Add(projectIDRetryInterval)
+		if err != nil && !f.projectWarned {
+			f.projectWarning = err
+		}
+		return
+	}
+	f.bgProjectID = id
+	f.projectWarning = nil
+}
+
+// detectionWarning returns the error of failed background project ID
+// detection, once.
+func (f *Formatter) detectionWarning() error {
+	if !f.NonBlockingDetection {
+		return nil
+	}
+	f.projectMu.Lock()
+	defer f.projectMu.Unlock()
+	err := f.projectWarning
+	if err != nil {
+		f.projectWarning = nil
+		f.projectWarned = true
+	}
+	return err
+}
+
+// serviceContext returns configured ServiceContext, or the detected one if
+// it's not set.
+func (f *Formatter) serviceContext() *ServiceContext {
+	if f.ServiceContext != nil {
+		return f.ServiceContext
+	}
+	if f.ResourceDetectors != nil {
+		if r := f.resource(); r != nil {
+			return r.ServiceContext
+		}
+		return nil
+	}
+	f.serviceContextOnce.Do(func() {
+		f.detectedServiceContext = serviceContextDetector()
+	})
+	return f.detectedServiceContext
+}
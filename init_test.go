@@ -0,0 +1,177 @@
+package appengine
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestInit(t *testing.T) {
+	if err := (&Formatter{ProjectID: "p"}).Init(); err != nil {
+		t.Errorf("Init() with ProjectID set returned error: %v", err)
+	}
+	if err := (&Formatter{}).Init(); err == nil {
+		t.Error("Init() did not return error when project ID can't be detected")
+	}
+}
+
+func TestLazyInitOnce(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	defer stubProjectIDDetector(func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls > 1 {
+			return "", errors.New("called more than once")
+		}
+		return "lazy-project", nil
+	})()
+
+	formatter := &Formatter{TraceKey: "trace"}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := formatter.Format(log.WithField("trace", "abc")); err != nil {
+				t.Error("Unable to format entry: ", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entry := formatToMap(t, formatter, log.WithField("trace", "abc"))
+	if entry[traceKey] != "projects/lazy-project/traces/abc" {
+		t.Errorf("%s = %v, want projects/lazy-project/traces/abc", traceKey, entry[traceKey])
+	}
+	if calls != 1 {
+		t.Errorf("project ID detected %d times, want 1", calls)
+	}
+}
+
+func TestNonBlockingDetection(t *testing.T) {
+	release := make(chan struct{})
+	defer stubProjectIDDetector(func() (string, error) {
+		<-release
+		return "slow-project", nil
+	})()
+
+	formatter := &Formatter{TraceKey: "trace", NonBlockingDetection: true}
+	entry := formatToMap(t, formatter, log.WithField("trace", "abc"))
+	if entry[traceKey] != "abc" {
+		t.Errorf("%s = %v while detection is in progress, want abc", traceKey, entry[traceKey])
+	}
+
+	close(release)
+	for i := 0; formatter.backgroundProjectID() == ""; i++ {
+		if i > 1000 {
+			t.Fatal("project ID was not detected in background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	entry = formatToMap(t, formatter, log.WithField("trace", "abc"))
+	if entry[traceKey] != "projects/slow-project/traces/abc" {
+		t.Errorf("%s = %v, want projects/slow-project/traces/abc", traceKey, entry[traceKey])
+	}
+}
+
+func TestNonBlockingDetectionWarning(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	defer stubProjectIDDetector(func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return "", errors.New("metadata server is down")
+	})()
+
+	formatter := &Formatter{TraceKey: "trace", NonBlockingDetection: true}
+	if _, err := formatter.Format(log.WithField("trace", "abc")); err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+	for i := 0; ; i++ {
+		formatter.projectMu.Lock()
+		detecting := formatter.projectDetecting
+		formatter.projectMu.Unlock()
+		if !detecting {
+			break
+		}
+		if i > 1000 {
+			t.Fatal("background detection did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i, wantLines := range []int{2, 1} {
+		b, err := formatter.Format(log.WithField("trace", "abc"))
+		if err != nil {
+			t.Fatal("Unable to format entry: ", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		if len(lines) != wantLines {
+			t.Fatalf("Format #%d returned %d lines, want %d: %s", i+2, len(lines), wantLines, b)
+		}
+		if wantLines == 2 {
+			warning := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(lines[0]), &warning); err != nil {
+				t.Fatal("Unable to unmarshal warning entry: ", err)
+			}
+			if warning["severity"] != "WARNING" || warning["error"] != "metadata server is down" {
+				t.Errorf("Got warning entry %v", warning)
+			}
+		}
+	}
+	if calls != 1 {
+		t.Errorf("project ID detected %d times, want 1", calls)
+	}
+}
+
+func TestNewStartsDetection(t *testing.T) {
+	started := make(chan struct{})
+	defer stubProjectIDDetector(func() (string, error) {
+		close(started)
+		return "detected", nil
+	})()
+	New()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("New() didn't start project ID detection")
+	}
+	if id, _ := detectedProjectID(); id != "detected" {
+		t.Errorf("detectedProjectID() = %q, want detected", id)
+	}
+	New(WithProjectID("p"))
+}
+
+func TestBlockingDetectionRetry(t *testing.T) {
+	defer func(d time.Duration) { projectIDRetryInterval = d }(projectIDRetryInterval)
+	projectIDRetryInterval = 0
+	var mu sync.Mutex
+	down := true
+	defer stubProjectIDDetector(func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return "", errors.New("metadata server is down")
+		}
+		return "late-project", nil
+	})()
+
+	formatter := &Formatter{TraceKey: "trace"}
+	if entry := formatToMap(t, formatter, log.WithField("trace", "abc")); entry[traceKey] != "abc" {
+		t.Errorf("%s = %v while detection fails, want abc", traceKey, entry[traceKey])
+	}
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	if entry := formatToMap(t, formatter, log.WithField("trace", "abc")); entry[traceKey] != "projects/late-project/traces/abc" {
+		t.Errorf("%s = %v, want projects/late-project/traces/abc", traceKey, entry[traceKey])
+	}
+}
@@ -0,0 +1,36 @@
+package appengine
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const insertIDKey = "logging.googleapis.com/insertId"
+
+// insertID returns insertId for the entry, or an empty string if it should
+// not have one.
+func (f *Formatter) insertID(entry *log.Entry) string {
+	if v, ok := lookupField(entry, f.InsertIDKey); ok {
+		if id := fmt.Sprint(v); id != "" {
+			return id
+		}
+	}
+	if f.GenerateInsertID {
+		return newUUID()
+	}
+	return ""
+}
+
+// newUUID returns a random (version 4) UUID. Returns an empty string if the
+// system source of randomness fails.
+func newUUID() string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return ""
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
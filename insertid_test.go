@@ -0,0 +1,42 @@
+package appengine
+
+import (
+	"regexp"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestInsertIDKey(t *testing.T) {
+	formatter := &Formatter{InsertIDKey: "id", GenerateInsertID: true}
+
+	entry := formatToMap(t, formatter, log.WithField("id", "abc"))
+	if entry[insertIDKey] != "abc" {
+		t.Errorf("%s = %v, want abc", insertIDKey, entry[insertIDKey])
+	}
+	if _, set := entry["id"]; set {
+		t.Error("id field is not removed")
+	}
+}
+
+func TestGenerateInsertID(t *testing.T) {
+	formatter := &Formatter{GenerateInsertID: true}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first := formatToMap(t, formatter, log.WithField("a", 1))
+	second := formatToMap(t, formatter, log.WithField("a", 1))
+
+	id, _ := first[insertIDKey].(string)
+	if !uuid.MatchString(id) {
+		t.Errorf("%s = %v, want UUID", insertIDKey, first[insertIDKey])
+	}
+	if first[insertIDKey] == second[insertIDKey] {
+		t.Errorf("Same %s generated twice: %v", insertIDKey, id)
+	}
+
+	formatter.GenerateInsertID = false
+	entry := formatToMap(t, formatter, log.WithField("a", 1))
+	if _, set := entry[insertIDKey]; set {
+		t.Errorf("%s generated when disabled", insertIDKey)
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/gelraen/appengine-formatter/kitlog
+
+go 1.13
+
+require (
+	github.com/gelraen/appengine-formatter v0.0.0-20261017060711-b0b2221ed9f8
+	github.com/go-kit/log v0.2.1
+	github.com/sirupsen/logrus v1.4.1
+)
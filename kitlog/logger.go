@@ -0,0 +1,98 @@
+// Package kitlog provides a go-kit log.Logger rendering entries with the
+// appengine Formatter:
+//
+//	logger := kitlog.NewLogger(os.Stderr, &appengine.Formatter{})
+//	level.Info(logger).Log("msg", "Starting", "port", 8080)
+//
+// Keyvals are converted into logrus fields, except for "msg", which becomes
+// the entry message, "ts" with a time.Time value, which becomes the entry
+// timestamp, and "level", which selects the severity. Level values are
+// either logrus level names, as produced by go-kit log/level package, or
+// Cloud Logging severity names, like "notice" or "critical".
+package kitlog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/go-kit/log"
+	"github.com/sirupsen/logrus"
+)
+
+// Keys of the keyvals that are not converted into fields.
+const (
+	MessageKey = "msg"
+	TimeKey    = "ts"
+	LevelKey   = "level"
+)
+
+type logger struct {
+	formatter *appengine.Formatter
+	out       io.Writer
+	mu        sync.Mutex
+}
+
+// NewLogger returns a logger writing entries formatted by f to w. Entries
+// without level are logged with INFO severity.
+func NewLogger(w io.Writer, f *appengine.Formatter) log.Logger {
+	return &logger{formatter: f, out: w}
+}
+
+func (l *logger) Log(keyvals ...interface{}) error {
+	entry := &logrus.Entry{Data: make(logrus.Fields, len(keyvals)/2), Time: time.Now(), Level: logrus.InfoLevel}
+	var severity appengine.Severity
+	for i := 0; i < len(keyvals); i += 2 {
+		k := fmt.Sprint(keyvals[i])
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		switch k {
+		case MessageKey:
+			entry.Message = fmt.Sprint(v)
+			continue
+		case TimeKey:
+			if t, ok := v.(time.Time); ok {
+				entry.Time = t
+				continue
+			}
+		case LevelKey:
+			name := fmt.Sprint(v)
+			if lvl, err := logrus.ParseLevel(name); err == nil {
+				entry.Level = lvl
+				continue
+			}
+			if s := appengine.Severity(strings.ToUpper(name)); isSeverity(s) {
+				severity = s
+				continue
+			}
+		}
+		entry.Data[k] = v
+	}
+	if severity != "" {
+		entry.Data = appengine.WithSeverity(entry, severity).Data
+	}
+
+	b, err := l.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.out.Write(b)
+	return err
+}
+
+func isSeverity(s appengine.Severity) bool {
+	switch s {
+	case appengine.SeverityDefault, appengine.SeverityDebug, appengine.SeverityInfo,
+		appengine.SeverityNotice, appengine.SeverityWarning, appengine.SeverityError,
+		appengine.SeverityCritical, appengine.SeverityAlert, appengine.SeverityEmergency:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package kitlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+func TestLogger(t *testing.T) {
+	ts := time.Unix(1500000000, 123)
+	for _, test := range []struct {
+		desc     string
+		log      func(log.Logger) error
+		severity string
+	}{
+		{"no level", func(l log.Logger) error { return l.Log("msg", "hello") }, "INFO"},
+		{"debug", func(l log.Logger) error { return level.Debug(l).Log("msg", "hello") }, "DEBUG"},
+		{"warn", func(l log.Logger) error { return level.Warn(l).Log("msg", "hello") }, "WARNING"},
+		{"error", func(l log.Logger) error { return level.Error(l).Log("msg", "hello") }, "ERROR"},
+		{"notice", func(l log.Logger) error { return l.Log("level", "notice", "msg", "hello") }, "NOTICE"},
+	} {
+		b := &bytes.Buffer{}
+		logger := log.With(NewLogger(b, &appengine.Formatter{}), "ts", ts, "component", "api")
+		if err := test.log(log.With(logger, "err", errors.New("wild walrus"))); err != nil {
+			t.Fatalf("%s: Log failed: %v", test.desc, err)
+		}
+
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: Unable to unmarshal formatted entry: %v", test.desc, err)
+		}
+		if entry["severity"] != test.severity {
+			t.Errorf("%s: severity = %v, want %s", test.desc, entry["severity"], test.severity)
+		}
+		if entry["message"] != "hello" || entry["component"] != "api" || entry["err"] != "wild walrus" {
+			t.Errorf("%s: got %v", test.desc, entry)
+		}
+		if _, ok := entry["msg"]; ok {
+			t.Errorf("%s: msg keyval not consumed", test.desc)
+		}
+		if ts := entry["timestamp"].(map[string]interface{}); ts["seconds"] != float64(1500000000) || ts["nanos"] != float64(123) {
+			t.Errorf("%s: timestamp = %v", test.desc, ts)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package appengine
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+const labelsKey = "logging.googleapis.com/labels"
+
+// WithLabels returns a new entry with the given labels added to the ones
+// already attached to the entry. Labels are emitted under
+// "logging.googleapis.com/labels" special key, regardless of Formatter.LabelsKey.
+func WithLabels(entry *log.Entry, labels map[string]string) *log.Entry {
+	existing, _ := entry.Data[labelsKey].(map[string]string)
+	merged := make(map[string]string, len(existing)+len(labels))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return entry.WithField(labelsKey, merged)
+}
+
+// entryLabels returns labels for the entry, or nil if there are none. Labels
+// added with WithLabels take precedence over the field named by LabelsKey,
+// then go labels derived from the entry context, static labels set on the
+// Formatter, and then detected environment labels.
+func (f *Formatter) entryLabels(entry *log.Entry) map[string]string {
+	var labels map[string]string
+	add := func(m map[string]string) {
+		if len(m) == 0 {
+			return
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(m))
+		}
+		for k, v := range m {
+			labels[k] = v
+		}
+	}
+	add(f.environmentLabels())
+	add(f.Labels)
+	if f.SchemaVersion != "" {
+		add(map[string]string{"log_schema_version": f.SchemaVersion})
+	}
+	if entry.Context != nil && f.ContextLabels != nil {
+		add(f.ContextLabels(entry.Context))
+	}
+	if v, ok := lookupField(entry, f.LabelsKey); ok {
+		m, _ := v.(map[string]string)
+		add(m)
+	}
+	m, _ := entry.Data[labelsKey].(map[string]string)
+	add(m)
+	return labels
+}
@@ -0,0 +1,102 @@
+package appengine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestLabelsKey(t *testing.T) {
+	formatter := &Formatter{LabelsKey: "labels"}
+
+	entry := formatToMap(t, formatter, log.WithField("labels", map[string]string{"a": "1"}))
+
+	want := map[string]interface{}{"a": "1"}
+	if !reflect.DeepEqual(entry[labelsKey], want) {
+		t.Errorf("%s = %v, want %v", labelsKey, entry[labelsKey], want)
+	}
+	if _, set := entry["labels"]; set {
+		t.Error("labels field is not removed")
+	}
+}
+
+func TestWithLabels(t *testing.T) {
+	formatter := &Formatter{LabelsKey: "labels"}
+
+	e := log.WithField("labels", map[string]string{"a": "1", "b": "1"})
+	e = WithLabels(e, map[string]string{"b": "2"})
+	e = WithLabels(e, map[string]string{"c": "3"})
+	entry := formatToMap(t, formatter, e)
+
+	want := map[string]interface{}{"a": "1", "b": "2", "c": "3"}
+	if !reflect.DeepEqual(entry[labelsKey], want) {
+		t.Errorf("%s = %v, want %v", labelsKey, entry[labelsKey], want)
+	}
+}
+
+func TestLabelsKeyWrongType(t *testing.T) {
+	formatter := &Formatter{LabelsKey: "labels"}
+
+	entry := formatToMap(t, formatter, log.WithField("labels", "not a map"))
+
+	if entry["labels"] != "not a map" {
+		t.Errorf("labels field changed, got %v", entry["labels"])
+	}
+	if _, set := entry[labelsKey]; set {
+		t.Errorf("%s set, got %v", labelsKey, entry[labelsKey])
+	}
+}
+
+func TestStaticLabels(t *testing.T) {
+	formatter := &Formatter{Labels: map[string]string{"service": "api", "ring": "canary"}}
+
+	entry := formatToMap(t, formatter, WithLabels(log.WithField("a", 1), map[string]string{"ring": "prod"}))
+
+	want := map[string]interface{}{"service": "api", "ring": "prod"}
+	if !reflect.DeepEqual(entry[labelsKey], want) {
+		t.Errorf("%s = %v, want %v", labelsKey, entry[labelsKey], want)
+	}
+	if formatter.Labels["ring"] != "canary" {
+		t.Error("Formatter.Labels modified")
+	}
+}
+
+type tenantKey struct{}
+
+func TestContextLabels(t *testing.T) {
+	formatter := &Formatter{
+		Labels: map[string]string{"tenant": "none", "service": "api"},
+		ContextLabels: func(ctx context.Context) map[string]string {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return map[string]string{"tenant": tenant, "principal": "user@" + tenant}
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	e := WithLabels(log.WithContext(ctx), map[string]string{"principal": "override"})
+	entry := formatToMap(t, formatter, e)
+
+	want := map[string]interface{}{"tenant": "acme", "service": "api", "principal": "override"}
+	if !reflect.DeepEqual(entry[labelsKey], want) {
+		t.Errorf("%s = %v, want %v", labelsKey, entry[labelsKey], want)
+	}
+
+	entry = formatToMap(t, formatter, log.WithField("a", 1))
+	want = map[string]interface{}{"tenant": "none", "service": "api"}
+	if !reflect.DeepEqual(entry[labelsKey], want) {
+		t.Errorf("%s without context = %v, want %v", labelsKey, entry[labelsKey], want)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	formatter := &Formatter{SchemaVersion: "2"}
+
+	entry := formatToMap(t, formatter, log.WithField("a", 1))
+
+	want := map[string]interface{}{"log_schema_version": "2"}
+	if !reflect.DeepEqual(entry[labelsKey], want) {
+		t.Errorf("%s = %v, want %v", labelsKey, entry[labelsKey], want)
+	}
+}
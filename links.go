@@ -0,0 +1,28 @@
+package appengine
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// LogsURL returns a link to Logs Explorer in Cloud Console for the given
+// project, with the query pre-filled. See
+// https://cloud.google.com/logging/docs/view/logging-query-language for the
+// query syntax.
+func LogsURL(project, query string) string {
+	return "https://console.cloud.google.com/logs/query;query=" + url.PathEscape(query) +
+		"?" + url.Values{"project": {project}}.Encode()
+}
+
+// TraceLogsURL returns a link to Logs Explorer showing all entries of the
+// given trace. trace can be either a raw trace ID, which is then qualified
+// with the project, or a full trace resource name, in which case project is
+// taken from it.
+func TraceLogsURL(project, trace string) string {
+	if p, _, ok := splitTrace(trace); ok {
+		project = p
+	} else {
+		trace = "projects/" + project + "/traces/" + trace
+	}
+	return LogsURL(project, "trace="+strconv.Quote(trace))
+}
@@ -0,0 +1,13 @@
+package appengine
+
+import "testing"
+
+func TestTraceLogsURL(t *testing.T) {
+	want := "https://console.cloud.google.com/logs/query;query=trace=%22projects%2Fp%2Ftraces%2Fabc%22?project=p"
+	if got := TraceLogsURL("p", "abc"); got != want {
+		t.Errorf("TraceLogsURL(p, abc) = %s, want %s", got, want)
+	}
+	if got := TraceLogsURL("other", "projects/p/traces/abc"); got != want {
+		t.Errorf("TraceLogsURL(other, projects/p/traces/abc) = %s, want %s", got, want)
+	}
+}
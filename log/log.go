@@ -0,0 +1,89 @@
+// Package log implements the API of the deprecated
+// google.golang.org/appengine/log package on top of logrus and the appengine
+// Formatter, to ease migrating legacy App Engine apps to the second
+// generation runtimes. In most cases replacing the import path and
+// appengine.NewContext calls is enough:
+//
+//	import "github.com/gelraen/appengine-formatter/log"
+//
+//	func main() {
+//		logrus.SetFormatter(log.NewFormatter())
+//		// ...
+//	}
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		ctx := log.NewContext(r)
+//		log.Infof(ctx, "Serving %s", r.URL.Path)
+//	}
+package log
+
+import (
+	"context"
+	"net/http"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+)
+
+// Keys of the fields NewContext stores the request trace under.
+const (
+	TraceKey        = "trace"
+	SpanIDKey       = "spanId"
+	TraceSampledKey = "traceSampled"
+)
+
+// Logger is used by the logging functions of this package.
+var Logger = logrus.StandardLogger()
+
+// NewFormatter returns a Formatter that emits the trace stored by NewContext
+// under the corresponding special keys.
+func NewFormatter() *appengine.Formatter {
+	return &appengine.Formatter{
+		TraceKey:        TraceKey,
+		SpanIDKey:       SpanIDKey,
+		TraceSampledKey: TraceSampledKey,
+	}
+}
+
+// NewContext returns the context of the request, carrying the trace from
+// X-Cloud-Trace-Context header, so entries logged with it are correlated
+// with the request.
+func NewContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	tc, ok := appengine.ParseCloudTraceContext(r.Header.Get("X-Cloud-Trace-Context"))
+	if !ok {
+		return ctx
+	}
+	ctx = appengine.PushField(ctx, TraceKey, tc.TraceID)
+	if tc.SpanID != "" {
+		ctx = appengine.PushField(ctx, SpanIDKey, tc.SpanID)
+	}
+	return appengine.PushField(ctx, TraceSampledKey, tc.Sampled)
+}
+
+// Debugf formats its arguments according to the format, analogous to
+// fmt.Printf, and records the text as a log message at Debug level.
+func Debugf(ctx context.Context, format string, args ...interface{}) {
+	Logger.WithContext(ctx).Debugf(format, args...)
+}
+
+// Infof is like Debugf, but at Info level.
+func Infof(ctx context.Context, format string, args ...interface{}) {
+	Logger.WithContext(ctx).Infof(format, args...)
+}
+
+// Warningf is like Debugf, but at Warning level.
+func Warningf(ctx context.Context, format string, args ...interface{}) {
+	Logger.WithContext(ctx).Warnf(format, args...)
+}
+
+// Errorf is like Debugf, but at Error level.
+func Errorf(ctx context.Context, format string, args ...interface{}) {
+	Logger.WithContext(ctx).Errorf(format, args...)
+}
+
+// Criticalf is like Debugf, but at Critical level. Unlike logrus' Fatal and
+// Panic levels, it doesn't terminate the program.
+func Criticalf(ctx context.Context, format string, args ...interface{}) {
+	appengine.WithSeverity(Logger.WithContext(ctx), appengine.SeverityCritical).Errorf(format, args...)
+}
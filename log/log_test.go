@@ -0,0 +1,41 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCriticalf(t *testing.T) {
+	defer func(l *logrus.Logger) { Logger = l }(Logger)
+	b := &bytes.Buffer{}
+	Logger = logrus.New()
+	Logger.Out = b
+	f := NewFormatter()
+	f.ProjectID = "my-project"
+	Logger.Formatter = f
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	Criticalf(NewContext(r), "hello %s", "walrus")
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	want := map[string]interface{}{
+		"message":                              "hello walrus",
+		"severity":                             "CRITICAL",
+		"logging.googleapis.com/trace":         "projects/my-project/traces/105445aa7843bc8bf206b12000100000",
+		"logging.googleapis.com/spanId":        "0000000000000001",
+		"logging.googleapis.com/trace_sampled": true,
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+}
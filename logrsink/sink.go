@@ -0,0 +1,152 @@
+// Package logrsink provides a logr.LogSink rendering entries with the
+// appengine Formatter, so controller-runtime and other logr users emit Cloud
+// Logging structured entries:
+//
+//	logger := logrsink.New(os.Stderr, &appengine.Formatter{}, 1)
+//
+// V-level 0 is mapped to INFO severity and higher V-levels to DEBUG. Names
+// added with WithName are joined with "/" and emitted as "logger" field.
+package logrsink
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// NameKey is the key of the field logger name is emitted under.
+const NameKey = "logger"
+
+type sink struct {
+	formatter *appengine.Formatter
+	out       io.Writer
+	mu        *sync.Mutex
+	logger    *logrus.Logger
+	verbosity int
+	callDepth int
+	name      string
+	values    logrus.Fields
+}
+
+// New returns a logr.Logger writing entries formatted by f to w. Entries
+// with V-level above verbosity are discarded.
+func New(w io.Writer, f *appengine.Formatter, verbosity int) logr.Logger {
+	return logr.New(NewSink(w, f, verbosity))
+}
+
+// NewSink returns a logr.LogSink writing entries formatted by f to w.
+// Entries with V-level above verbosity are discarded.
+func NewSink(w io.Writer, f *appengine.Formatter, verbosity int) logr.LogSink {
+	logger := logrus.New()
+	logger.ReportCaller = true
+	return &sink{
+		formatter: f,
+		out:       w,
+		mu:        &sync.Mutex{},
+		logger:    logger,
+		verbosity: verbosity,
+		values:    logrus.Fields{},
+	}
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.callDepth = info.CallDepth
+}
+
+func (s *sink) Enabled(level int) bool {
+	return level <= s.verbosity
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	l := logrus.InfoLevel
+	if level > 0 {
+		l = logrus.DebugLevel
+	}
+	s.write(l, msg, nil, keysAndValues)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.write(logrus.ErrorLevel, msg, err, keysAndValues)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := *s
+	c.values = addValues(s.values, keysAndValues)
+	return &c
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	c := *s
+	if c.name != "" {
+		c.name += "/" + name
+	} else {
+		c.name = name
+	}
+	return &c
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	c := *s
+	c.callDepth += depth
+	return &c
+}
+
+// write formats and writes the entry. It must be called directly from the
+// logr.LogSink methods, for the caller to be determined correctly.
+func (s *sink) write(level logrus.Level, msg string, err error, keysAndValues []interface{}) {
+	data := addValues(s.values, keysAndValues)
+	if s.name != "" {
+		data[NameKey] = s.name
+	}
+	if err != nil {
+		data[logrus.ErrorKey] = err
+	}
+	entry := &logrus.Entry{
+		Logger:  s.logger,
+		Data:    data,
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+	}
+	// Skip write, the LogSink method and the frames added by logr.
+	if pc, _, _, ok := runtime.Caller(s.callDepth + 2); ok {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		entry.Caller = &frame
+	}
+
+	b, ferr := s.formatter.Format(entry)
+	if ferr != nil {
+		b = []byte(fmt.Sprintf("Failed to format entry, %v\n", ferr))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.out.Write(b)
+}
+
+// addValues returns a copy of fields with key/value pairs added. Non-string
+// keys are converted with fmt.Sprint, and a missing value of the last key is
+// set to nil.
+func addValues(fields logrus.Fields, keysAndValues []interface{}) logrus.Fields {
+	r := make(logrus.Fields, len(fields)+len(keysAndValues)/2)
+	for k, v := range fields {
+		r[k] = v
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		k, ok := keysAndValues[i].(string)
+		if !ok {
+			k = fmt.Sprint(keysAndValues[i])
+		}
+		var v interface{}
+		if i+1 < len(keysAndValues) {
+			v = keysAndValues[i+1]
+		}
+		r[k] = v
+	}
+	return r
+}
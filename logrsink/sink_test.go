@@ -0,0 +1,69 @@
+package logrsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	appengine "github.com/gelraen/appengine-formatter"
+)
+
+func decode(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	d := json.NewDecoder(b)
+	for d.More() {
+		entry := make(map[string]interface{})
+		if err := d.Decode(&entry); err != nil {
+			t.Fatal("Unable to unmarshal formatted entry: ", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSink(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := New(b, &appengine.Formatter{}, 1).WithName("controller").WithName("pods").WithValues("pod", "web-1")
+
+	logger.Info("Reconciling", "attempt", 2)
+	logger.V(1).Info("Details")
+	logger.V(2).Info("Too verbose")
+	logger.Error(errors.New("wild walrus"), "Reconcile failed", "odd")
+
+	entries := decode(t, b)
+	if len(entries) != 3 {
+		t.Fatalf("Got %d entries, want 3", len(entries))
+	}
+	for i, want := range []struct {
+		message  string
+		severity string
+	}{
+		{"Reconciling", "INFO"},
+		{"Details", "DEBUG"},
+		{"Reconcile failed", "ERROR"},
+	} {
+		e := entries[i]
+		if e["message"] != want.message || e["severity"] != want.severity {
+			t.Errorf("Entry #%d: got message %v and severity %v, want %q and %q", i, e["message"], e["severity"], want.message, want.severity)
+		}
+		if e[NameKey] != "controller/pods" || e["pod"] != "web-1" {
+			t.Errorf("Entry #%d: got %s %v and pod %v", i, NameKey, e[NameKey], e["pod"])
+		}
+		l, _ := e["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+		if name, _ := l["function"].(string); !strings.HasSuffix(name, ".TestSink") {
+			t.Errorf("Entry #%d: got sourceLocation %v, want location in TestSink", i, l)
+		}
+	}
+	if entries[0]["attempt"] != float64(2) {
+		t.Errorf("attempt = %v, want 2", entries[0]["attempt"])
+	}
+	if entries[2]["error"] != "wild walrus" {
+		t.Errorf("error = %v, want wild walrus", entries[2]["error"])
+	}
+	if v, ok := entries[2]["odd"]; !ok || v != nil {
+		t.Errorf("odd = %v, want null", v)
+	}
+}
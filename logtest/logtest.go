@@ -0,0 +1,101 @@
+// Package logtest routes entries formatted by the appengine Formatter to
+// test logs and records them for assertions:
+//
+//	func TestHandler(t *testing.T) {
+//		rec := logtest.New(t, &appengine.Formatter{})
+//		handle(rec.Logger)
+//		if e := rec.Last(); e.Severity() != "ERROR" {
+//			t.Errorf("Got severity %s, want ERROR", e.Severity())
+//		}
+//	}
+package logtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+)
+
+// Entry is a decoded formatted entry.
+type Entry map[string]interface{}
+
+// Severity returns severity of the entry.
+func (e Entry) Severity() string {
+	s, _ := e["severity"].(string)
+	return s
+}
+
+// Message returns message of the entry.
+func (e Entry) Message() string {
+	s, _ := e["message"].(string)
+	return s
+}
+
+// Recorder logs formatted entries with t.Log and records them decoded.
+type Recorder struct {
+	// Logger is the logger to pass to the code under test. Its level is
+	// set to logrus.TraceLevel.
+	Logger *logrus.Logger
+
+	t       testing.TB
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New returns a Recorder with a logger using formatter f.
+func New(t testing.TB, f *appengine.Formatter) *Recorder {
+	r := &Recorder{t: t}
+	r.Logger = logrus.New()
+	r.Logger.Formatter = f
+	r.Logger.Out = writer{r}
+	r.Logger.Level = logrus.TraceLevel
+	return r
+}
+
+// Entries returns all entries recorded so far.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Last returns the last recorded entry, or nil if there's none.
+func (r *Recorder) Last() Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return nil
+	}
+	return r.entries[len(r.entries)-1]
+}
+
+// Reset forgets the recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+type writer struct {
+	r *Recorder
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	w.r.t.Log(string(bytes.TrimSuffix(p, []byte("\n"))))
+	d := json.NewDecoder(bytes.NewReader(p))
+	for d.More() {
+		var e Entry
+		if err := d.Decode(&e); err != nil {
+			w.r.t.Errorf("Unable to decode formatted entry: %v", err)
+			break
+		}
+		w.r.mu.Lock()
+		w.r.entries = append(w.r.entries, e)
+		w.r.mu.Unlock()
+	}
+	return len(p), nil
+}
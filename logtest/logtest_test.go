@@ -0,0 +1,30 @@
+package logtest
+
+import (
+	"testing"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := New(t, &appengine.Formatter{PrettyPrint: true})
+	rec.Logger.WithField("user", "walrus").Info("hello")
+	appengine.WithSeverity(logrus.NewEntry(rec.Logger), appengine.SeverityNotice).Warn("noticed")
+
+	entries := rec.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Recorded %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Severity() != "INFO" || e.Message() != "hello" || e["user"] != "walrus" {
+		t.Errorf("Got first entry %v", e)
+	}
+	if e := rec.Last(); e.Severity() != "NOTICE" || e.Message() != "noticed" {
+		t.Errorf("Got last entry %v", e)
+	}
+
+	rec.Reset()
+	if e := rec.Last(); e != nil {
+		t.Errorf("Last() after Reset() = %v, want nil", e)
+	}
+}
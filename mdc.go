@@ -0,0 +1,115 @@
+package appengine
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type contextFieldsKey struct{}
+
+// contextFields is an immutable list of fields pushed into a context, most
+// recent first.
+type contextFields struct {
+	parent *contextFields
+	key    string
+	value  interface{}
+
+	// cache is shared by all fields of the list, so values derived from the
+	// fields are computed once per request rather than once per entry.
+	cache *requestCache
+}
+
+// maxCachedTraces limits the number of traces cached per request. Normally
+// there's only one.
+const maxCachedTraces = 8
+
+// requestCache holds values derived from request-scoped fields. nil cache is
+// valid and doesn't cache anything.
+type requestCache struct {
+	mu     sync.Mutex
+	traces map[traceCacheKey]resolvedTrace
+}
+
+func requestCacheFrom(ctx context.Context) *requestCache {
+	if ctx == nil {
+		return nil
+	}
+	if list, _ := ctx.Value(contextFieldsKey{}).(*contextFields); list != nil {
+		return list.cache
+	}
+	return nil
+}
+
+func (c *requestCache) trace(key traceCacheKey) (resolvedTrace, bool) {
+	if c == nil {
+		return resolvedTrace{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.traces[key]
+	return r, ok
+}
+
+func (c *requestCache) putTrace(key traceCacheKey, r resolvedTrace) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.traces == nil {
+		c.traces = map[traceCacheKey]resolvedTrace{}
+	}
+	if len(c.traces) < maxCachedTraces {
+		c.traces[key] = r
+	}
+}
+
+// PushField returns a copy of ctx carrying the field. Fields carried by the
+// context are added to every entry formatted with that context (see
+// logrus.Entry.WithContext), so request metadata doesn't need to be passed
+// around together with a logger. Fields set on the entry itself take
+// precedence, and later pushed values override earlier ones.
+func PushField(ctx context.Context, key string, value interface{}) context.Context {
+	parent, _ := ctx.Value(contextFieldsKey{}).(*contextFields)
+	cache := &requestCache{}
+	if parent != nil {
+		cache = parent.cache
+	}
+	return context.WithValue(ctx, contextFieldsKey{}, &contextFields{parent: parent, key: key, value: value, cache: cache})
+}
+
+// ContextFields returns fields pushed into the context with PushField, or
+// nil if there are none.
+func ContextFields(ctx context.Context) log.Fields {
+	list, _ := ctx.Value(contextFieldsKey{}).(*contextFields)
+	if list == nil {
+		return nil
+	}
+	fields := log.Fields{}
+	for ; list != nil; list = list.parent {
+		if _, set := fields[list.key]; !set {
+			fields[list.key] = list.value
+		}
+	}
+	return fields
+}
+
+// withContextFields returns the entry with fields carried by its context
+// merged in. The original entry is not modified.
+func withContextFields(entry *log.Entry) *log.Entry {
+	if entry.Context == nil {
+		return entry
+	}
+	fields := ContextFields(entry.Context)
+	if fields == nil {
+		return entry
+	}
+	for k, v := range entry.Data {
+		fields[k] = v
+	}
+	e := *entry
+	e.Data = fields
+	return &e
+}
@@ -0,0 +1,84 @@
+package appengine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestContextFields(t *testing.T) {
+	ctx := context.Background()
+	if fields := ContextFields(ctx); fields != nil {
+		t.Errorf("ContextFields() = %v, want nil", fields)
+	}
+
+	ctx = PushField(ctx, "a", 1)
+	child := PushField(PushField(ctx, "b", 2), "a", 3)
+
+	if fields, want := ContextFields(ctx), (log.Fields{"a": 1}); !reflect.DeepEqual(fields, want) {
+		t.Errorf("ContextFields(parent) = %v, want %v", fields, want)
+	}
+	if fields, want := ContextFields(child), (log.Fields{"a": 3, "b": 2}); !reflect.DeepEqual(fields, want) {
+		t.Errorf("ContextFields(child) = %v, want %v", fields, want)
+	}
+}
+
+func TestPushFieldFormatted(t *testing.T) {
+	formatter := &Formatter{TraceKey: "trace"}
+
+	ctx := PushField(context.Background(), "request_id", "r1")
+	ctx = PushField(ctx, "trace", "projects/p/traces/abc")
+	ctx = PushField(ctx, "user", "context")
+	e := log.WithContext(ctx).WithField("user", "entry")
+	entry := formatToMap(t, formatter, e)
+
+	if entry["request_id"] != "r1" {
+		t.Errorf("request_id = %v, want r1", entry["request_id"])
+	}
+	if entry["user"] != "entry" {
+		t.Errorf("user = %v, want entry", entry["user"])
+	}
+	if entry[traceKey] != "projects/p/traces/abc" {
+		t.Errorf("%s = %v, want projects/p/traces/abc", traceKey, entry[traceKey])
+	}
+	if len(e.Data) != 1 {
+		t.Errorf("Original entry modified: %v", e.Data)
+	}
+}
+
+func TestRequestCache(t *testing.T) {
+	defer stubProjectIDDetector(func() (string, error) { return "detected", nil })()
+	formatter := &Formatter{TraceKey: "trace", TraceURL: true}
+
+	ctx := PushField(context.Background(), "trace", "abc")
+	for _, ctx := range []context.Context{ctx, PushField(ctx, "a", 1)} {
+		entry := formatToMap(t, formatter, log.WithContext(ctx))
+		if entry[traceKey] != "projects/detected/traces/abc" {
+			t.Errorf("%s = %v, want projects/detected/traces/abc", traceKey, entry[traceKey])
+		}
+		if entry["trace_url"] == nil {
+			t.Error("trace_url not set")
+		}
+	}
+
+	cache := requestCacheFrom(ctx)
+	if r, ok := cache.trace(traceCacheKey{f: formatter, trace: "abc"}); !ok || r.name != "projects/detected/traces/abc" {
+		t.Errorf("Trace not cached: %+v", cache.traces)
+	}
+	other := &Formatter{TraceKey: "trace", ProjectID: "other"}
+	if entry := formatToMap(t, other, log.WithContext(ctx)); entry[traceKey] != "projects/other/traces/abc" || entry["trace_url"] != nil {
+		t.Errorf("Another formatter got %s = %v, trace_url = %v, want its own project and no URL", traceKey, entry[traceKey], entry["trace_url"])
+	}
+	perEntry := &Formatter{TraceKey: "trace", TraceProjectIDKey: "project"}
+	for _, project := range []string{"p1", "p2"} {
+		entry := formatToMap(t, perEntry, log.WithContext(ctx).WithField("project", project))
+		if want := "projects/" + project + "/traces/abc"; entry[traceKey] != want {
+			t.Errorf("%s = %v, want %s", traceKey, entry[traceKey], want)
+		}
+	}
+	if cache != requestCacheFrom(PushField(ctx, "b", 2)) {
+		t.Error("Cache is not shared with child contexts")
+	}
+}
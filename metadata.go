@@ -0,0 +1,120 @@
+package appengine
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Project ID detection state, guarded by projectIDMu. projectIDDone is
+	// closed when the detection in progress, if any, finishes.
+	projectIDMu      sync.Mutex
+	projectID        string
+	projectIDErr     error
+	projectIDRetryAt time.Time
+	projectIDDone    chan struct{}
+
+	// projectIDDetector does the actual detection, its results are cached
+	// by detectedProjectID. Replaced in tests.
+	projectIDDetector = detectProjectID
+
+	metadataClient = &http.Client{Timeout: 2 * time.Second}
+
+	// projectIDRetryInterval is how long failed project ID detection is
+	// cached for.
+	projectIDRetryInterval = time.Minute
+)
+
+// DetectProjectID returns ID of the Google Cloud project the program is
+// running in. It checks GOOGLE_CLOUD_PROJECT, GCP_PROJECT and GCLOUD_PROJECT
+// environment variables first, and then queries the metadata server. The
+// result is cached for the lifetime of the process, failures are cached for
+// a minute so an unavailable metadata server isn't queried on every call.
+// Returns an empty string if the project cannot be determined.
+func DetectProjectID() string {
+	id, _ := detectedProjectID()
+	return id
+}
+
+// detectedProjectID is like DetectProjectID, but also returns the reason why
+// project ID could not be determined. Concurrent callers share the
+// detection in progress, and the lock is not held while it runs, since it
+// may query the metadata server.
+func detectedProjectID() (string, error) {
+	projectIDMu.Lock()
+	for projectIDDone != nil {
+		done := projectIDDone
+		projectIDMu.Unlock()
+		<-done
+		projectIDMu.Lock()
+	}
+	if projectID != "" || time.Now().Before(projectIDRetryAt) {
+		id, err := projectID, projectIDErr
+		projectIDMu.Unlock()
+		return id, err
+	}
+	done := make(chan struct{})
+	projectIDDone = done
+	detect := projectIDDetector
+	projectIDMu.Unlock()
+
+	id, err := detect()
+
+	projectIDMu.Lock()
+	defer projectIDMu.Unlock()
+	if projectIDDone == done {
+		projectID, projectIDErr = id, err
+		projectIDRetryAt = time.Now().Add(projectIDRetryInterval)
+		projectIDDone = nil
+	}
+	close(done)
+	return id, err
+}
+
+func detectProjectID() (string, error) {
+	for _, v := range []string{"GOOGLE_CLOUD_PROJECT", "GCP_PROJECT", "GCLOUD_PROJECT"} {
+		if id := os.Getenv(v); id != "" {
+			return id, nil
+		}
+	}
+	return metadataValue("project/project-id")
+}
+
+// metadataValue fetches a value from the metadata server. GCE_METADATA_HOST
+// environment variable can be used to override the server address.
+func metadataValue(path string) (string, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		// Using the IP address directly avoids a DNS lookup that can be slow
+		// to fail when not running on Google Cloud.
+		host = "169.254.169.254"
+	}
+	req, err := http.NewRequest("GET", "http://"+host+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned %s for %q", resp.Status, path)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	v := strings.TrimSpace(string(b))
+	if v == "" {
+		return "", errors.New("metadata server returned empty value for " + path)
+	}
+	return v, nil
+}
@@ -0,0 +1,76 @@
+package appengine
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Tests must not depend on the environment they are running in.
+	projectIDDetector = func() (string, error) { return "", errors.New("disabled in tests") }
+	serviceContextDetector = func() *ServiceContext { return nil }
+	environmentLabelsDetector = func() map[string]string { return nil }
+	os.Exit(m.Run())
+}
+
+// setenv sets environment variable and returns a function restoring its
+// original value.
+func setenv(key, value string) func() {
+	old, set := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if set {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// stubProjectIDDetector replaces the project ID detector and resets the
+// cached detection result, and returns a function restoring the original
+// detector.
+func stubProjectIDDetector(d func() (string, error)) func() {
+	old := projectIDDetector
+	resetProjectIDDetector(d)
+	return func() { resetProjectIDDetector(old) }
+}
+
+func resetProjectIDDetector(d func() (string, error)) {
+	projectIDMu.Lock()
+	defer projectIDMu.Unlock()
+	projectIDDetector = d
+	projectID, projectIDErr, projectIDRetryAt, projectIDDone = "", nil, time.Time{}, nil
+}
+
+func TestDetectProjectIDFromEnv(t *testing.T) {
+	defer setenv("GOOGLE_CLOUD_PROJECT", "env-project")()
+
+	if got, _ := detectProjectID(); got != "env-project" {
+		t.Errorf("detectProjectID() = %q, want %q", got, "env-project")
+	}
+}
+
+func TestDetectProjectIDFromMetadata(t *testing.T) {
+	for _, v := range []string{"GOOGLE_CLOUD_PROJECT", "GCP_PROJECT", "GCLOUD_PROJECT"} {
+		defer setenv(v, "")()
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/project/project-id" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("metadata-project"))
+	}))
+	defer srv.Close()
+	defer setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))()
+
+	if got, err := detectProjectID(); got != "metadata-project" || err != nil {
+		t.Errorf("detectProjectID() = %q, %v, want %q", got, err, "metadata-project")
+	}
+}
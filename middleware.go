@@ -0,0 +1,29 @@
+package appengine
+
+import (
+	"context"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Middleware returns a handler that extracts the trace context from
+// X-Cloud-Trace-Context or W3C traceparent header of incoming requests and
+// stores it in the request context with WithTraceContext, before calling h.
+// Entries logged with the request context (see FromContext) are then
+// correlated with the request trace.
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tc, ok := traceContextFromRequest(r); ok {
+			r = r.WithContext(WithTraceContext(r.Context(), tc))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// FromContext returns an entry of the standard logger carrying ctx, with
+// the fields pushed into ctx (including the trace context stored by
+// Middleware) already set.
+func FromContext(ctx context.Context) *log.Entry {
+	return log.WithContext(ctx).WithFields(ContextFields(ctx))
+}
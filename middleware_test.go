@@ -0,0 +1,41 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMiddleware(t *testing.T) {
+	b := &bytes.Buffer{}
+	defer func(out io.Writer, f log.Formatter) {
+		log.SetOutput(out)
+		log.SetFormatter(f)
+	}(log.StandardLogger().Out, log.StandardLogger().Formatter)
+	log.SetOutput(b)
+	log.SetFormatter(&Formatter{ProjectID: "p"})
+
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := FromContext(r.Context())
+		if _, ok := e.Data[traceContextKey]; !ok {
+			t.Errorf("FromContext() returned entry without %s field", traceContextKey)
+		}
+		e.Info("handling")
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("traceparent", "00-"+testTraceID+"-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if entry[traceKey] != "projects/p/traces/"+testTraceID || entry[spanIDKey] != "00f067aa0ba902b7" {
+		t.Errorf("Got %s %v and %s %v", traceKey, entry[traceKey], spanIDKey, entry[spanIDKey])
+	}
+}
@@ -0,0 +1,35 @@
+package appengine
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// mainModulePath is the path of the main module, e.g.
+// "github.com/user/project". Replaced in tests.
+var mainModulePath = readMainModulePath()
+
+func readMainModulePath() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path == "command-line-arguments" {
+		return ""
+	}
+	return info.Main.Path
+}
+
+// trimModulePath returns the file path relative to the root of the main
+// module if the path contains the module path, which is the case for
+// binaries built with -trimpath or in GOPATH, and the path as is otherwise.
+func trimModulePath(file string) string {
+	if mainModulePath == "" {
+		return file
+	}
+	prefix := mainModulePath + "/"
+	if strings.HasPrefix(file, prefix) {
+		return file[len(prefix):]
+	}
+	if i := strings.LastIndex(file, "/"+prefix); i >= 0 {
+		return file[i+1+len(prefix):]
+	}
+	return file
+}
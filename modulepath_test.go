@@ -0,0 +1,31 @@
+package appengine
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestTrimModulePath(t *testing.T) {
+	defer func(p string) { mainModulePath = p }(mainModulePath)
+	mainModulePath = "github.com/user/project"
+
+	for _, test := range []struct{ file, want string }{
+		{"github.com/user/project/cmd/server/main.go", "cmd/server/main.go"},
+		{"/go/src/github.com/user/project/main.go", "main.go"},
+		{"github.com/user/project-other/main.go", "github.com/user/project-other/main.go"},
+		{"/home/user/project/main.go", "/home/user/project/main.go"},
+		{"runtime/proc.go", "runtime/proc.go"},
+	} {
+		if got := trimModulePath(test.file); got != test.want {
+			t.Errorf("trimModulePath(%q) = %q, want %q", test.file, got, test.want)
+		}
+	}
+
+	frame := &runtime.Frame{Function: "main.main", File: "github.com/user/project/main.go"}
+	if _, file := (&Formatter{}).frameLocation(frame); file != "main.go" {
+		t.Errorf("file = %q, want main.go", file)
+	}
+	if _, file := (&Formatter{KeepModulePath: true}).frameLocation(frame); file != frame.File {
+		t.Errorf("file = %q with KeepModulePath, want %q", file, frame.File)
+	}
+}
@@ -0,0 +1,33 @@
+// Package oclog lets the appengine Formatter correlate entries with
+// OpenCensus spans, for services still instrumented with OpenCensus:
+//
+//	formatter := &appengine.Formatter{TraceExtractor: oclog.TraceContext}
+//	// ...
+//	logrus.WithContext(ctx).Info("Handled")
+//
+// Entries logged with a context carrying a span get its trace ID, span ID
+// and sampling decision in the corresponding special fields.
+package oclog
+
+import (
+	"context"
+	"encoding/hex"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"go.opencensus.io/trace"
+)
+
+// TraceContext returns the trace context of the span carried by ctx. It
+// returns false if there's no span.
+func TraceContext(ctx context.Context) (appengine.TraceContext, bool) {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return appengine.TraceContext{}, false
+	}
+	sc := span.SpanContext()
+	return appengine.TraceContext{
+		TraceID: hex.EncodeToString(sc.TraceID[:]),
+		SpanID:  hex.EncodeToString(sc.SpanID[:]),
+		Sampled: sc.IsSampled(),
+	}, true
+}
@@ -0,0 +1,44 @@
+package oclog
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+)
+
+func TestTraceContext(t *testing.T) {
+	ctx, span := trace.StartSpan(context.Background(), "op", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	sc := span.SpanContext()
+
+	b := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = b
+	logger.Formatter = &appengine.Formatter{ProjectID: "p", TraceExtractor: TraceContext}
+	logger.WithContext(ctx).Info("traced")
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	want := map[string]interface{}{
+		"logging.googleapis.com/trace":         "projects/p/traces/" + hex.EncodeToString(sc.TraceID[:]),
+		"logging.googleapis.com/spanId":        hex.EncodeToString(sc.SpanID[:]),
+		"logging.googleapis.com/trace_sampled": true,
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+
+	if _, ok := TraceContext(context.Background()); ok {
+		t.Error("TraceContext() returned true for context without span")
+	}
+}
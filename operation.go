@@ -0,0 +1,51 @@
+package appengine
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+const operationKey = "logging.googleapis.com/operation"
+
+// Operation groups entries belonging to the same long-running operation. If
+// a value of this type, or a pointer to it, is attached to the entry under
+// any key, it is emitted under "logging.googleapis.com/operation" special
+// key. See
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntryOperation
+type Operation struct {
+	// ID is an arbitrary operation identifier. Entries with the same
+	// identifier are assumed to be part of the same operation.
+	ID string `json:"id,omitempty"`
+	// Producer is an arbitrary producer identifier. The combination of ID
+	// and Producer must be globally unique.
+	Producer string `json:"producer,omitempty"`
+	// First is set if this is the first entry of the operation.
+	First bool `json:"first,omitempty"`
+	// Last is set if this is the last entry of the operation.
+	Last bool `json:"last,omitempty"`
+}
+
+// WithOperation returns a new entry marked as a part of the operation.
+func WithOperation(entry *log.Entry, id, producer string) *log.Entry {
+	return entry.WithField(operationKey, &Operation{ID: id, Producer: producer})
+}
+
+// BeginOperation returns a new entry marked as the first entry of the
+// operation.
+func BeginOperation(entry *log.Entry, id, producer string) *log.Entry {
+	return entry.WithField(operationKey, &Operation{ID: id, Producer: producer, First: true})
+}
+
+// EndOperation returns a new entry marked as the last entry of the operation.
+func EndOperation(entry *log.Entry, id, producer string) *log.Entry {
+	return entry.WithField(operationKey, &Operation{ID: id, Producer: producer, Last: true})
+}
+
+func isOperation(v interface{}) bool {
+	switch v := v.(type) {
+	case Operation:
+		return true
+	case *Operation:
+		return v != nil
+	}
+	return false
+}
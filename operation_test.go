@@ -0,0 +1,43 @@
+package appengine
+
+import (
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestOperation(t *testing.T) {
+	formatter := &Formatter{}
+
+	tests := []struct {
+		entry *log.Entry
+		want  map[string]interface{}
+	}{
+		{
+			BeginOperation(log.WithField("a", 1), "op1", "importer"),
+			map[string]interface{}{"id": "op1", "producer": "importer", "first": true},
+		},
+		{
+			WithOperation(log.WithField("a", 1), "op1", "importer"),
+			map[string]interface{}{"id": "op1", "producer": "importer"},
+		},
+		{
+			EndOperation(log.WithField("a", 1), "op1", "importer"),
+			map[string]interface{}{"id": "op1", "producer": "importer", "last": true},
+		},
+		{
+			log.WithField("op", Operation{ID: "op2"}),
+			map[string]interface{}{"id": "op2"},
+		},
+	}
+	for _, test := range tests {
+		entry := formatToMap(t, formatter, test.entry)
+		if !reflect.DeepEqual(entry[operationKey], test.want) {
+			t.Errorf("%s = %v, want %v", operationKey, entry[operationKey], test.want)
+		}
+		if _, set := entry["op"]; set {
+			t.Error("op field is not removed")
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package appengine
+
+import (
+	"context"
+)
+
+// Option configures a Formatter created with New.
+type Option func(*Formatter)
+
+// New returns a Formatter configured with the given options. If no project
+// ID is configured, its detection is started in the background right away,
+// so that the first entries with a trace don't wait for the metadata server
+// inside Format.
+func New(opts ...Option) *Formatter {
+	f := newFormatter(opts...)
+	f.startDetection()
+	return f
+}
+
+// newFormatter is like New, but doesn't start project ID detection.
+func newFormatter(opts ...Option) *Formatter {
+	f := &Formatter{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// WithProjectID sets the project ID used to qualify trace IDs, see
+// Formatter.ProjectID.
+func WithProjectID(projectID string) Option {
+	return func(f *Formatter) {
+		f.ProjectID = projectID
+	}
+}
+
+// WithDefaultLabels adds labels to every entry, see Formatter.Labels.
+// Labels given in later options take precedence. (WithLabels attaches
+// labels to a single entry.)
+func WithDefaultLabels(labels map[string]string) Option {
+	return func(f *Formatter) {
+		merged := make(map[string]string, len(f.Labels)+len(labels))
+		for k, v := range f.Labels {
+			merged[k] = v
+		}
+		for k, v := range labels {
+			merged[k] = v
+		}
+		f.Labels = merged
+	}
+}
+
+// WithServiceContext sets the service context of reported errors, see
+// Formatter.ServiceContext.
+func WithServiceContext(service, version string) Option {
+	return func(f *Formatter) {
+		f.ServiceContext = &ServiceContext{Service: service, Version: version}
+	}
+}
+
+// WithTraceFromContext sets the function used to get the trace context of
+// entries from their context, see Formatter.TraceExtractor.
+func WithTraceFromContext(extract func(ctx context.Context) (TraceContext, bool)) Option {
+	return func(f *Formatter) {
+		f.TraceExtractor = extract
+	}
+}
@@ -0,0 +1,30 @@
+package appengine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	extract := func(ctx context.Context) (TraceContext, bool) { return TraceContext{}, false }
+	f := New(
+		WithProjectID("my-project"),
+		WithDefaultLabels(map[string]string{"a": "1", "b": "1"}),
+		WithDefaultLabels(map[string]string{"b": "2"}),
+		WithServiceContext("api", "v1"),
+		WithTraceFromContext(extract),
+	)
+	if f.ProjectID != "my-project" {
+		t.Errorf("ProjectID = %q, want my-project", f.ProjectID)
+	}
+	if want := map[string]string{"a": "1", "b": "2"}; !reflect.DeepEqual(f.Labels, want) {
+		t.Errorf("Labels = %v, want %v", f.Labels, want)
+	}
+	if want := (&ServiceContext{Service: "api", Version: "v1"}); !reflect.DeepEqual(f.ServiceContext, want) {
+		t.Errorf("ServiceContext = %+v, want %+v", f.ServiceContext, want)
+	}
+	if f.TraceExtractor == nil {
+		t.Error("TraceExtractor not set")
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/gelraen/appengine-formatter/otellog
+
+go 1.13
+
+require (
+	github.com/gelraen/appengine-formatter v0.0.0-20261017060711-b0b2221ed9f8
+	github.com/sirupsen/logrus v1.4.1
+	go.opentelemetry.io/otel/trace v1.11.1
+)
@@ -0,0 +1,31 @@
+// Package otellog lets the appengine Formatter correlate entries with
+// OpenTelemetry spans, so logs line up with traces exported to Cloud Trace:
+//
+//	formatter := &appengine.Formatter{TraceExtractor: otellog.TraceContext}
+//	// ...
+//	logrus.WithContext(ctx).Info("Handled")
+//
+// Entries logged with a context carrying an active span get its trace ID,
+// span ID and sampling decision in the corresponding special fields.
+package otellog
+
+import (
+	"context"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceContext returns the trace context of the span carried by ctx. It
+// returns false if there's no span or its span context is invalid.
+func TraceContext(ctx context.Context) (appengine.TraceContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return appengine.TraceContext{}, false
+	}
+	return appengine.TraceContext{
+		TraceID: sc.TraceID().String(),
+		SpanID:  sc.SpanID().String(),
+		Sampled: sc.IsSampled(),
+	}, true
+}
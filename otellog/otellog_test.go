@@ -0,0 +1,47 @@
+package otellog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContext(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	b := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = b
+	logger.Formatter = &appengine.Formatter{ProjectID: "p", TraceExtractor: TraceContext}
+	logger.WithContext(ctx).Info("traced")
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	want := map[string]interface{}{
+		"logging.googleapis.com/trace":         "projects/p/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		"logging.googleapis.com/spanId":        "00f067aa0ba902b7",
+		"logging.googleapis.com/trace_sampled": true,
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+
+	if _, ok := TraceContext(context.Background()); ok {
+		t.Error("TraceContext() returned true for context without span")
+	}
+}
@@ -0,0 +1,59 @@
+package appengine
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// processKey is the key of the field Process is emitted under.
+const processKey = "process"
+
+// Process identifies the process that produced an entry, to tell apart
+// instances running the same service version.
+type Process struct {
+	PID        int       `json:"pid"`
+	Hostname   string    `json:"hostname,omitempty"`
+	Executable string    `json:"executable,omitempty"`
+	StartTime  time.Time `json:"startTime"`
+}
+
+var (
+	processOnce sync.Once
+	process     *Process
+
+	// startTime approximates the process start time.
+	startTime = time.Now()
+)
+
+// CurrentProcess returns Process describing the running program. Start time
+// is the time this package was initialized. The result is cached for the
+// lifetime of the process.
+func CurrentProcess() *Process {
+	processOnce.Do(func() {
+		process = &Process{PID: os.Getpid(), StartTime: startTime}
+		process.Hostname, _ = os.Hostname()
+		process.Executable, _ = os.Executable()
+	})
+	return process
+}
+
+// processField returns the value of "process" field for the entry, or nil
+// if it should be omitted.
+func (f *Formatter) processField(entry *log.Entry) *Process {
+	if f.Process == nil {
+		return nil
+	}
+	if f.ProcessInterval <= 0 {
+		return f.Process
+	}
+	f.processMu.Lock()
+	defer f.processMu.Unlock()
+	if !f.processLast.IsZero() && entry.Time.Sub(f.processLast) < f.ProcessInterval {
+		return nil
+	}
+	f.processLast = entry.Time
+	return f.Process
+}
@@ -0,0 +1,48 @@
+package appengine
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestCurrentProcess(t *testing.T) {
+	p := CurrentProcess()
+	if p.PID != os.Getpid() {
+		t.Errorf("PID = %d, want %d", p.PID, os.Getpid())
+	}
+	if p.Executable == "" || p.StartTime.IsZero() {
+		t.Errorf("Got incomplete %+v", p)
+	}
+}
+
+func TestProcessField(t *testing.T) {
+	start := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	formatter := &Formatter{
+		Process:         &Process{PID: 42, Hostname: "host", Executable: "/app", StartTime: start},
+		ProcessInterval: time.Minute,
+	}
+
+	for _, test := range []struct {
+		after time.Duration
+		want  bool
+	}{
+		{0, true},
+		{30 * time.Second, false},
+		{time.Minute, true},
+		{time.Minute + time.Second, false},
+	} {
+		e := log.WithTime(start.Add(test.after))
+		entry := formatToMap(t, formatter, e)
+		p, ok := entry[processKey].(map[string]interface{})
+		if ok != test.want {
+			t.Errorf("%s: %s = %v, want present: %v", test.after, processKey, entry[processKey], test.want)
+			continue
+		}
+		if ok && (p["pid"] != float64(42) || p["hostname"] != "host" || p["startTime"] != "2019-04-01T12:00:00Z") {
+			t.Errorf("%s: got %s %v", test.after, processKey, p)
+		}
+	}
+}
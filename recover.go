@@ -0,0 +1,49 @@
+package appengine
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RecoverHandler returns a handler that calls h and recovers from panics in
+// it. A recovered panic is logged as a CRITICAL entry shaped as an Error
+// Reporting event, carrying the request and the stack of the panicking
+// goroutine, and the client gets 500 Internal Server Error response, unless
+// h has already started writing the response, in which case the panic is
+// only logged. http.ErrAbortHandler panics are passed through.
+func RecoverHandler(logger *log.Logger, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			req := NewHTTPRequest(r)
+			req.Status = rw.status
+			if req.Status == 0 {
+				req.Status = http.StatusInternalServerError
+			}
+			req.Latency = time.Since(start)
+			msg := fmt.Sprint("panic: ", v)
+			e := logger.WithContext(r.Context()).WithFields(log.Fields{
+				"@type":       reportedErrorEventType,
+				"stack_trace": msg + "\n\n" + string(debug.Stack()),
+				"panic":       fmt.Sprint(v),
+			})
+			WithSeverity(e, SeverityCritical).WithField(httpRequestKey, req).Error(msg)
+			if rw.status == 0 {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(rw, r)
+	})
+}
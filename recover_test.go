@@ -0,0 +1,87 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRecoverHandler(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	logger.Formatter = &Formatter{ErrorReporting: true, StackTrace: true}
+
+	h := RecoverHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("wild walrus")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/walrus", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Response code = %d, want 500", w.Code)
+	}
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if entry["severity"] != "CRITICAL" {
+		t.Errorf("severity = %v, want CRITICAL", entry["severity"])
+	}
+	if entry["@type"] != reportedErrorEventType {
+		t.Errorf("@type = %v, want %s", entry["@type"], reportedErrorEventType)
+	}
+	if _, set := entry["fields.@type"]; set {
+		t.Error("@type duplicated")
+	}
+	stack, _ := entry["stack_trace"].(string)
+	if !strings.HasPrefix(stack, "panic: wild walrus\n\ngoroutine ") || !strings.Contains(stack, "TestRecoverHandler") {
+		t.Errorf("Unexpected stack_trace: %q", stack)
+	}
+	req, _ := entry[httpRequestKey].(map[string]interface{})
+	if req["status"] != float64(500) || req["requestUrl"] != "/walrus" {
+		t.Errorf("Unexpected %s: %v", httpRequestKey, req)
+	}
+}
+
+func TestRecoverHandlerAbort(t *testing.T) {
+	h := RecoverHandler(log.New(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recover() = %v, want http.ErrAbortHandler", v)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestRecoverHandlerAfterWrite(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	logger.Formatter = &Formatter{}
+
+	h := RecoverHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		panic("wild walrus")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/walrus", nil))
+	if w.Code != http.StatusAccepted || w.Body.String() != "partial" {
+		t.Errorf("Response = %d %q, want the one written by the handler", w.Code, w.Body.String())
+	}
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if req, _ := entry[httpRequestKey].(map[string]interface{}); req["status"] != float64(http.StatusAccepted) {
+		t.Errorf("%s = %v, want status 202", httpRequestKey, entry[httpRequestKey])
+	}
+}
@@ -0,0 +1,101 @@
+package appengine
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// isRedactedKey reports whether values under the key k are replaced with
+// "[REDACTED]", see RedactKeys.
+func (f *Formatter) isRedactedKey(k string) bool {
+	for _, r := range f.RedactKeys {
+		if strings.EqualFold(k, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactField returns the value of the entry field k with RedactKeys
+// applied. Values are copied if anything has to be redacted, so the entry is
+// never modified.
+func (f *Formatter) redactField(k string, v interface{}) interface{} {
+	if len(f.RedactKeys) == 0 {
+		return v
+	}
+	r, _ := f.redact(k, v)
+	return r
+}
+
+// redact returns v with RedactKeys applied, and whether anything was
+// redacted. Nested maps with string keys and slices of interface{} are
+// inspected, values of other types are left as is.
+func (f *Formatter) redact(k string, v interface{}) (interface{}, bool) {
+	if f.isRedactedKey(k) {
+		return redacted, true
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return f.redactMap(v)
+	case log.Fields:
+		m, changed := f.redactMap(v)
+		return log.Fields(m), changed
+	case map[string]string:
+		var out map[string]string
+		for k := range v {
+			if f.isRedactedKey(k) {
+				out = make(map[string]string, len(v))
+				break
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		for k, s := range v {
+			if f.isRedactedKey(k) {
+				s = redacted
+			}
+			out[k] = s
+		}
+		return out, true
+	case []interface{}:
+		var out []interface{}
+		for i, e := range v {
+			r, changed := f.redact("", e)
+			if changed && out == nil {
+				out = make([]interface{}, len(v))
+				copy(out, v)
+			}
+			if changed {
+				out[i] = r
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	}
+	return v, false
+}
+
+func (f *Formatter) redactMap(m map[string]interface{}) (map[string]interface{}, bool) {
+	var out map[string]interface{}
+	for k, e := range m {
+		r, changed := f.redact(k, e)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for k, e := range m {
+				out[k] = e
+			}
+		}
+		out[k] = r
+	}
+	if out == nil {
+		return m, false
+	}
+	return out, true
+}
@@ -0,0 +1,49 @@
+package appengine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRedactKeys(t *testing.T) {
+	formatter := &Formatter{RedactKeys: []string{"password", "token"}}
+	req := map[string]interface{}{
+		"user":     "alice",
+		"Password": "hunter2",
+		"headers":  map[string]string{"Token": "abc", "Accept": "*/*"},
+		"items":    []interface{}{log.Fields{"token": "def", "id": 1}, "plain"},
+	}
+	e := log.WithFields(log.Fields{"password": "hunter2", "req": req, "n": 1})
+	entry := formatToMap(t, formatter, e)
+
+	want := map[string]interface{}{
+		"user":     "alice",
+		"Password": "[REDACTED]",
+		"headers":  map[string]interface{}{"Token": "[REDACTED]", "Accept": "*/*"},
+		"items":    []interface{}{map[string]interface{}{"token": "[REDACTED]", "id": float64(1)}, "plain"},
+	}
+	if entry["password"] != "[REDACTED]" {
+		t.Errorf("password = %v, want [REDACTED]", entry["password"])
+	}
+	if !reflect.DeepEqual(entry["req"], want) {
+		t.Errorf("req = %v, want %v", entry["req"], want)
+	}
+	if entry["n"] != float64(1) {
+		t.Errorf("n = %v, want 1", entry["n"])
+	}
+	if req["Password"] != "hunter2" || req["headers"].(map[string]string)["Token"] != "abc" {
+		t.Errorf("entry fields modified: %v", req)
+	}
+}
+
+func TestRedactKeysSummaryMessage(t *testing.T) {
+	formatter := &Formatter{RedactKeys: []string{"password"}, SummaryConformance: true}
+	e := log.WithFields(log.Fields{"password": "hunter2", "req": map[string]interface{}{"password": "hunter2"}})
+	entry := formatToMap(t, formatter, e)
+	if msg, _ := entry["message"].(string); strings.Contains(msg, "hunter2") {
+		t.Errorf("message = %q, leaks redacted value", msg)
+	}
+}
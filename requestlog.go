@@ -0,0 +1,150 @@
+package appengine
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type requestLogKey struct{}
+
+// requestLog tracks the maximum severity of entries logged during a request.
+type requestLog struct {
+	mu          sync.Mutex
+	maxSeverity Severity
+}
+
+func requestLogFrom(ctx context.Context) *requestLog {
+	if ctx == nil {
+		return nil
+	}
+	rl, _ := ctx.Value(requestLogKey{}).(*requestLog)
+	return rl
+}
+
+func (rl *requestLog) record(s Severity) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if s.rank() > rl.maxSeverity.rank() {
+		rl.maxSeverity = s
+	}
+}
+
+func (rl *requestLog) severity() Severity {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.maxSeverity
+}
+
+// RequestLogHandler returns a handler that emits a parent entry for every
+// request served by h, the way App Engine request logs work. Entries logged
+// with the request context (see FromContext) are correlated with the
+// request by its trace, taken from the request headers, or generated if
+// there's none. The parent entry is logged with logger after h returns or
+// panics. It carries httpRequest with the response status, size and
+// latency, and its severity is the maximum of INFO and the severities of
+// entries formatted with the request context, or at least ERROR if h
+// panicked.
+//
+// Logs Explorer nests entries under the parent only if the parent is in a
+// different log (logName) than them. Logging agents put everything written
+// to a stream into the same log, so logger must write somewhere that ends up
+// in a separate log, e.g. stderr while the rest goes to stdout on App Engine
+// and Cloud Run, or a logging agent input with its own log name. Otherwise
+// the parent and its children are shown as siblings, still linked by the
+// trace.
+func RequestLogHandler(logger *log.Logger, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		tc, ok := traceContextFromRequest(r)
+		if !ok {
+			tc = TraceContext{TraceID: newTraceID()}
+		}
+		ctx := WithTraceContext(r.Context(), tc)
+		rl := &requestLog{maxSeverity: SeverityInfo}
+		rw := &responseRecorder{ResponseWriter: w}
+		panicked := true
+		// The entry is logged while the panic, if any, propagates, without
+		// recovering from it.
+		defer func() {
+			req := NewHTTPRequest(r)
+			req.Status = rw.status
+			if panicked {
+				rl.record(SeverityError)
+				if req.Status == 0 {
+					req.Status = http.StatusInternalServerError
+				}
+			}
+			if req.Status == 0 {
+				req.Status = http.StatusOK
+			}
+			req.ResponseSize = rw.size
+			req.Latency = time.Since(start)
+			e := WithSeverity(logger.WithContext(ctx), rl.severity())
+			e.WithField(httpRequestKey, req).Info(r.Method + " " + r.URL.RequestURI())
+		}()
+		h.ServeHTTP(rw, r.WithContext(context.WithValue(ctx, requestLogKey{}, rl)))
+		panicked = false
+	})
+}
+
+// newTraceID returns a random trace ID.
+func newTraceID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// responseRecorder records status and size of the response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Hijack implements http.Hijacker, if the underlying ResponseWriter does,
+// e.g. for websocket handlers.
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter doesn't implement http.Hijacker")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Flush implements http.Flusher, if the underlying ResponseWriter does.
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,118 @@
+package appengine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRequestLogHandler(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	logger.Formatter = &Formatter{ProjectID: "p"}
+
+	h := RequestLogHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).Info("child")
+		logger.WithContext(r.Context()).Warn("another child")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/tea?pot=1", nil))
+
+	var entries []map[string]interface{}
+	d := json.NewDecoder(b)
+	for d.More() {
+		entry := make(map[string]interface{})
+		if err := d.Decode(&entry); err != nil {
+			t.Fatal("Unable to unmarshal formatted entry: ", err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Got %d entries, want 3", len(entries))
+	}
+
+	parent := entries[2]
+	trace, _ := parent[traceKey].(string)
+	if !summaryTraceRe.MatchString(trace) {
+		t.Errorf("Parent %s = %q, want generated trace", traceKey, trace)
+	}
+	for i, e := range entries[:2] {
+		if e[traceKey] != trace {
+			t.Errorf("Child #%d %s = %v, want %q", i, traceKey, e[traceKey], trace)
+		}
+	}
+	if parent["severity"] != "WARNING" || parent["message"] != "GET /tea?pot=1" {
+		t.Errorf("Got parent severity %v and message %v, want WARNING and GET /tea?pot=1", parent["severity"], parent["message"])
+	}
+	req, _ := parent[httpRequestKey].(map[string]interface{})
+	if req["status"] != float64(http.StatusTeapot) || req["responseSize"] != "15" {
+		t.Errorf("Got %s %v", httpRequestKey, req)
+	}
+}
+
+func TestRequestLogHandlerPanic(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	logger.Formatter = &Formatter{ProjectID: "p"}
+
+	h := RequestLogHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("wild walrus")
+	}))
+	func() {
+		defer func() {
+			if v := recover(); v != "wild walrus" {
+				t.Errorf("recover() = %v, want the panic to propagate", v)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if entry["severity"] != "ERROR" {
+		t.Errorf("severity = %v, want ERROR", entry["severity"])
+	}
+	if req, _ := entry[httpRequestKey].(map[string]interface{}); req["status"] != float64(500) {
+		t.Errorf("%s = %v, want status 500", httpRequestKey, entry[httpRequestKey])
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (w *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+func TestRequestLogHandlerHijack(t *testing.T) {
+	logger := log.New()
+	logger.Out = &bytes.Buffer{}
+	h := RequestLogHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("ResponseWriter doesn't implement http.Hijacker")
+		}
+		if _, _, err := hj.Hijack(); err != nil {
+			t.Errorf("Hijack failed: %v", err)
+		}
+	}))
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/ws", nil))
+	if !w.hijacked {
+		t.Error("Hijack was not passed through")
+	}
+}
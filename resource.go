@@ -0,0 +1,180 @@
+package appengine
+
+import (
+	"os"
+	"strings"
+)
+
+// Resource describes the environment the program is running in.
+type Resource struct {
+	// Type is the monitored resource type, e.g. "gae_app" or
+	// "cloud_run_revision".
+	Type string
+	// Labels are added to every entry, see Formatter.Labels.
+	Labels map[string]string
+	// ServiceContext is used when Formatter.ServiceContext is not set.
+	ServiceContext *ServiceContext
+}
+
+// ResourceDetector detects the environment the program is running in.
+type ResourceDetector interface {
+	// Detect returns the detected resource, or nil if the program is not
+	// running in the environment recognized by the detector.
+	Detect() *Resource
+}
+
+// ResourceDetectorFunc is an adapter to use ordinary functions as
+// ResourceDetector.
+type ResourceDetectorFunc func() *Resource
+
+// Detect calls f().
+func (f ResourceDetectorFunc) Detect() *Resource {
+	return f()
+}
+
+// Built-in resource detectors.
+var (
+	// AppEngineDetector recognizes App Engine by GAE_SERVICE environment
+	// variable.
+	AppEngineDetector ResourceDetector = ResourceDetectorFunc(detectAppEngine)
+	// CloudFunctionsDetector recognizes Cloud Functions (2nd gen) by
+	// FUNCTION_TARGET environment variable.
+	CloudFunctionsDetector ResourceDetector = ResourceDetectorFunc(detectCloudFunctions)
+	// CloudRunDetector recognizes Cloud Run by K_SERVICE environment
+	// variable.
+	CloudRunDetector ResourceDetector = ResourceDetectorFunc(detectCloudRun)
+	// GKEDetector recognizes GKE by KUBERNETES_SERVICE_HOST environment
+	// variable. Cluster name and location not set in the environment are
+	// queried from the metadata server.
+	GKEDetector ResourceDetector = ResourceDetectorFunc(detectGKE)
+	// GCEDetector recognizes Compute Engine by querying the metadata server,
+	// so it may take a while to give up elsewhere.
+	GCEDetector ResourceDetector = ResourceDetectorFunc(detectGCE)
+	// LocalDetector always succeeds, reporting a "global" resource labeled
+	// with the host name.
+	LocalDetector ResourceDetector = ResourceDetectorFunc(detectLocal)
+)
+
+// DefaultResourceDetectors is the chain of built-in detectors in the order
+// they are tried.
+var DefaultResourceDetectors = []ResourceDetector{
+	AppEngineDetector,
+	CloudFunctionsDetector,
+	CloudRunDetector,
+	GKEDetector,
+	GCEDetector,
+	LocalDetector,
+}
+
+// DetectResource returns the resource reported by the first detector that
+// recognizes the environment, or nil if none does.
+func DetectResource(detectors ...ResourceDetector) *Resource {
+	for _, d := range detectors {
+		if r := d.Detect(); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+func detectAppEngine() *Resource {
+	s := os.Getenv("GAE_SERVICE")
+	if s == "" {
+		return nil
+	}
+	return &Resource{
+		Type: "gae_app",
+		Labels: nonEmptyLabels(map[string]string{
+			"module_id":     s,
+			"version_id":    os.Getenv("GAE_VERSION"),
+			"instance_name": os.Getenv("GAE_INSTANCE"),
+			"runtime":       os.Getenv("GAE_RUNTIME"),
+		}),
+		ServiceContext: &ServiceContext{Service: s, Version: os.Getenv("GAE_VERSION")},
+	}
+}
+
+func detectCloudFunctions() *Resource {
+	t := os.Getenv("FUNCTION_TARGET")
+	if t == "" {
+		return nil
+	}
+	r := &Resource{
+		Type: "cloud_function",
+		Labels: nonEmptyLabels(map[string]string{
+			"function_name":   os.Getenv("K_SERVICE"),
+			"function_target": t,
+			"revision_name":   os.Getenv("K_REVISION"),
+		}),
+	}
+	if s := os.Getenv("K_SERVICE"); s != "" {
+		r.ServiceContext = &ServiceContext{Service: s, Version: os.Getenv("K_REVISION")}
+	}
+	return r
+}
+
+func detectCloudRun() *Resource {
+	s := os.Getenv("K_SERVICE")
+	if s == "" {
+		return nil
+	}
+	return &Resource{
+		Type: "cloud_run_revision",
+		Labels: nonEmptyLabels(map[string]string{
+			"service_name":       s,
+			"revision_name":      os.Getenv("K_REVISION"),
+			"configuration_name": os.Getenv("K_CONFIGURATION"),
+		}),
+		ServiceContext: &ServiceContext{Service: s, Version: os.Getenv("K_REVISION")},
+	}
+}
+
+func detectGKE() *Resource {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return nil
+	}
+	return &Resource{Type: "k8s_container", Labels: nonEmptyLabels(gkeLabels(true))}
+}
+
+// detectGKEFromEnvironment is like detectGKE, but never queries the metadata
+// server.
+func detectGKEFromEnvironment() *Resource {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return nil
+	}
+	return &Resource{Type: "k8s_container", Labels: nonEmptyLabels(gkeLabels(false))}
+}
+
+func detectGCE() *Resource {
+	id, err := metadataValue("instance/id")
+	if err != nil {
+		return nil
+	}
+	zone, _ := metadataValue("instance/zone")
+	// The zone is returned as "projects/<number>/zones/<zone>".
+	zone = zone[strings.LastIndex(zone, "/")+1:]
+	return &Resource{
+		Type: "gce_instance",
+		Labels: nonEmptyLabels(map[string]string{
+			"instance_id": id,
+			"zone":        zone,
+		}),
+	}
+}
+
+func detectLocal() *Resource {
+	h, _ := os.Hostname()
+	return &Resource{Type: "global", Labels: nonEmptyLabels(map[string]string{"hostname": h})}
+}
+
+// resource returns the resource detected with ResourceDetectors, or nil if
+// they are not set.
+func (f *Formatter) resource() *Resource {
+	if f.ResourceDetectors == nil {
+		return nil
+	}
+	f.resourceOnce.Do(func() {
+		f.detectedResource = DetectResource(f.ResourceDetectors...)
+	})
+	return f.detectedResource
+}
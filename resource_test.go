@@ -0,0 +1,113 @@
+package appengine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestDetectResource(t *testing.T) {
+	none := ResourceDetectorFunc(func() *Resource { return nil })
+	first := &Resource{Type: "first"}
+	second := &Resource{Type: "second"}
+	detectors := []ResourceDetector{
+		none,
+		ResourceDetectorFunc(func() *Resource { return first }),
+		ResourceDetectorFunc(func() *Resource { return second }),
+	}
+	if r := DetectResource(detectors...); r != first {
+		t.Errorf("DetectResource() = %+v, want %+v", r, first)
+	}
+	if r := DetectResource(none); r != nil {
+		t.Errorf("DetectResource() = %+v, want nil", r)
+	}
+}
+
+func TestDetectCloudRunResource(t *testing.T) {
+	for _, v := range []string{"GAE_SERVICE", "FUNCTION_TARGET", "K_CONFIGURATION"} {
+		defer setenv(v, "")()
+	}
+	defer setenv("K_SERVICE", "run-service")()
+	defer setenv("K_REVISION", "run-service-00001")()
+
+	want := &Resource{
+		Type:           "cloud_run_revision",
+		Labels:         map[string]string{"service_name": "run-service", "revision_name": "run-service-00001"},
+		ServiceContext: &ServiceContext{Service: "run-service", Version: "run-service-00001"},
+	}
+	if r := DetectResource(DefaultResourceDetectors...); !reflect.DeepEqual(r, want) {
+		t.Errorf("DetectResource() = %+v, want %+v", r, want)
+	}
+}
+
+func TestDetectGCEResource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/id":
+			w.Write([]byte("4520031799277581759"))
+		case "/computeMetadata/v1/instance/zone":
+			w.Write([]byte("projects/123456789/zones/us-central1-a"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))()
+
+	want := &Resource{
+		Type:   "gce_instance",
+		Labels: map[string]string{"instance_id": "4520031799277581759", "zone": "us-central1-a"},
+	}
+	if r := GCEDetector.Detect(); !reflect.DeepEqual(r, want) {
+		t.Errorf("GCEDetector.Detect() = %+v, want %+v", r, want)
+	}
+
+	srv.Close()
+	if r := GCEDetector.Detect(); r != nil {
+		t.Errorf("GCEDetector.Detect() = %+v without metadata server, want nil", r)
+	}
+}
+
+func TestLocalDetector(t *testing.T) {
+	r := LocalDetector.Detect()
+	if r == nil || r.Type != "global" {
+		t.Errorf("LocalDetector.Detect() = %+v, want global resource", r)
+	}
+}
+
+func TestFormatterResourceDetectors(t *testing.T) {
+	calls := 0
+	formatter := &Formatter{
+		ErrorReporting: true,
+		Labels:         map[string]string{"zone": "static"},
+		ResourceDetectors: []ResourceDetector{ResourceDetectorFunc(func() *Resource {
+			calls++
+			return &Resource{
+				Type:           "gce_instance",
+				Labels:         map[string]string{"instance_id": "1", "zone": "us-central1-a"},
+				ServiceContext: &ServiceContext{Service: "api", Version: "v1"},
+			}
+		})},
+	}
+
+	e := log.WithField("a", 1)
+	e.Level = log.ErrorLevel
+	entry := formatToMap(t, formatter, e)
+	wantLabels := map[string]interface{}{"instance_id": "1", "zone": "static"}
+	if !reflect.DeepEqual(entry[labelsKey], wantLabels) {
+		t.Errorf("%s = %v, want %v", labelsKey, entry[labelsKey], wantLabels)
+	}
+	wantContext := map[string]interface{}{"service": "api", "version": "v1"}
+	if !reflect.DeepEqual(entry["serviceContext"], wantContext) {
+		t.Errorf("serviceContext = %v, want %v", entry["serviceContext"], wantContext)
+	}
+
+	formatToMap(t, formatter, e)
+	if calls != 1 {
+		t.Errorf("detector called %d times, want 1", calls)
+	}
+}
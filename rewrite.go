@@ -0,0 +1,231 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Keys commonly used by third-party JSON loggers, mapped by Rewriter to the
+// keys recognized by Cloud Logging. If the target key is present, it's used,
+// otherwise the first present key of each list. The rest are passed through.
+var (
+	rewriteMessageKeys   = []string{"msg", "message"}
+	rewriteSeverityKeys  = []string{"level", "lvl", "severity"}
+	rewriteTimestampKeys = []string{"ts", "time", "timestamp"}
+)
+
+// maxRewriterBuffer limits the size of an incomplete line Rewriter buffers.
+const maxRewriterBuffer = 1 << 20
+
+var severityNames = map[string]Severity{
+	"trace":     SeverityDebug,
+	"debug":     SeverityDebug,
+	"info":      SeverityInfo,
+	"notice":    SeverityNotice,
+	"warn":      SeverityWarning,
+	"warning":   SeverityWarning,
+	"err":       SeverityError,
+	"error":     SeverityError,
+	"dpanic":    SeverityCritical,
+	"crit":      SeverityCritical,
+	"critical":  SeverityCritical,
+	"fatal":     SeverityCritical,
+	"panic":     SeverityCritical,
+	"alert":     SeverityAlert,
+	"emerg":     SeverityEmergency,
+	"emergency": SeverityEmergency,
+}
+
+// Rewriter is an io.Writer that converts JSON log lines written by other
+// programs, e.g. a child process, into entries Cloud Logging understands.
+// Common message, level, timestamp and caller keys (as used by zap, zerolog,
+// logrus, pino and others) are remapped to "message", "severity",
+// "timestamp" and "logging.googleapis.com/sourceLocation", and all other
+// keys are passed through. Lines that are not JSON objects are emitted as
+// messages. Incomplete lines longer than 1 MiB are emitted as messages
+// without waiting for the line terminator. Rewriter is safe for concurrent
+// use.
+type Rewriter struct {
+	out io.Writer
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewRewriter returns a Rewriter writing rewritten lines to w.
+func NewRewriter(w io.Writer) *Rewriter {
+	return &Rewriter{out: w}
+}
+
+// Write rewrites all complete lines in p. Incomplete last line is buffered
+// until the rest of it is written or Flush is called.
+func (r *Rewriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf = append(r.buf, p...)
+	for {
+		i := bytes.IndexByte(r.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := r.buf[:i]
+		r.buf = r.buf[i+1:]
+		if err := r.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	if len(r.buf) > maxRewriterBuffer {
+		line := r.buf
+		r.buf = nil
+		if err := r.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	if len(r.buf) == 0 {
+		r.buf = nil
+	}
+	return len(p), nil
+}
+
+// Flush rewrites the buffered incomplete line, if any.
+func (r *Rewriter) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	line := r.buf
+	r.buf = nil
+	return r.writeLine(line)
+}
+
+func (r *Rewriter) writeLine(line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	b := &bytes.Buffer{}
+	data, _ := rewriteLine(line)
+	if err := encodeObject(b, data, nil, false, nil); err != nil {
+		return err
+	}
+	_, err := r.out.Write(b.Bytes())
+	return err
+}
+
+// rewriteLine returns the fields of rewritten line, and the timestamp
+// stored under "timestamp" key if it was recognized and rewritten, or zero
+// time otherwise.
+func rewriteLine(line []byte) (log.Fields, time.Time) {
+	var data log.Fields
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	if err := d.Decode(&data); err != nil || d.More() || data == nil {
+		return log.Fields{"message": string(line)}, time.Time{}
+	}
+
+	if k, v, ok := sourceKey(data, "message", rewriteMessageKeys); ok && k != "message" {
+		delete(data, k)
+		data["message"] = v
+	}
+	if k, v, ok := sourceKey(data, "severity", rewriteSeverityKeys); ok {
+		if s, ok := parseSeverity(v); ok {
+			delete(data, k)
+			data["severity"] = s
+		}
+	}
+	var timestamp time.Time
+	if k, v, ok := sourceKey(data, "timestamp", rewriteTimestampKeys); ok {
+		if ts, ok := parseTimestamp(v); ok {
+			delete(data, k)
+			data["timestamp"] = map[string]interface{}{
+				"seconds": ts.Unix(),
+				"nanos":   ts.Nanosecond(),
+			}
+			timestamp = ts
+		}
+	}
+	if caller, ok := data["caller"].(string); ok {
+		if _, set := data[sourceLocationKey]; !set {
+			if i := strings.LastIndexByte(caller, ':'); i > 0 {
+				if line, err := strconv.Atoi(caller[i+1:]); err == nil {
+					delete(data, "caller")
+					data[sourceLocationKey] = map[string]interface{}{
+						"file": caller[:i],
+						"line": line,
+					}
+				}
+			}
+		}
+	}
+	return data, timestamp
+}
+
+// sourceKey returns the key to take the value of target key from: target
+// itself if present, so it's never overwritten, or the first present of keys.
+func sourceKey(data log.Fields, target string, keys []string) (string, interface{}, bool) {
+	if v, ok := data[target]; ok {
+		return target, v, true
+	}
+	for _, k := range keys {
+		if v, ok := data[k]; ok {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// parseSeverity recognizes level names and numeric pino/bunyan levels.
+func parseSeverity(v interface{}) (Severity, bool) {
+	switch v := v.(type) {
+	case string:
+		s, ok := severityNames[strings.ToLower(v)]
+		return s, ok
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return "", false
+		}
+		switch {
+		case n < 30:
+			return SeverityDebug, true
+		case n < 40:
+			return SeverityInfo, true
+		case n < 50:
+			return SeverityWarning, true
+		case n < 60:
+			return SeverityError, true
+		default:
+			return SeverityCritical, true
+		}
+	}
+	return "", false
+}
+
+// parseTimestamp recognizes RFC 3339 strings and numeric Unix time in
+// seconds or, for values too large to be seconds, milliseconds.
+func parseTimestamp(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil && n > 1e11 {
+			return time.Unix(0, n*int64(time.Millisecond)), true
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		if f > 1e11 {
+			f /= 1000
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))), true
+	}
+	return time.Time{}, false
+}
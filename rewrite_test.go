@@ -0,0 +1,65 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRewriter(t *testing.T) {
+	b := &bytes.Buffer{}
+	r := NewRewriter(b)
+
+	input := `{"level":"warn","ts":1500000000.5,"caller":"main.go:42","msg":"zap","user":"walrus"}
+{"level":30,"time":1500000000123,"msg":"pino","pid":1}
+{"severity":"NOTICE","timestamp":"2017-07-14T02:40:00.25Z","message":"already fine","msg":"kept"}
+{"level":"error","severity":"NOTICE","ts":1500000000,"timestamp":"2017-07-14T02:40:00.25Z","msg":"both"}
+
+plain text line
+{"level":"unknown","msg":"x"}
+{"partial":`
+	if _, err := r.Write([]byte(input)); err != nil {
+		t.Fatal("Write failed: ", err)
+	}
+	if _, err := r.Write([]byte(`true}`)); err != nil {
+		t.Fatal("Write failed: ", err)
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatal("Flush failed: ", err)
+	}
+
+	want := `{"logging.googleapis.com/sourceLocation":{"file":"main.go","line":42},"message":"zap","severity":"WARNING","timestamp":{"nanos":500000000,"seconds":1500000000},"user":"walrus"}
+{"message":"pino","pid":1,"severity":"INFO","timestamp":{"nanos":123000000,"seconds":1500000000}}
+{"message":"already fine","msg":"kept","severity":"NOTICE","timestamp":{"nanos":250000000,"seconds":1500000000}}
+{"level":"error","message":"both","severity":"NOTICE","timestamp":{"nanos":250000000,"seconds":1500000000},"ts":1500000000}
+{"message":"plain text line"}
+{"level":"unknown","message":"x"}
+{"partial":true}
+`
+	if b.String() != want {
+		t.Errorf("Got:\n%s\nwant:\n%s", b, want)
+	}
+}
+
+func TestRewriterLongLine(t *testing.T) {
+	b := &bytes.Buffer{}
+	r := NewRewriter(b)
+
+	chunk := []byte(strings.Repeat("x", 64*1024))
+	for i := 0; i <= maxRewriterBuffer/len(chunk); i++ {
+		if _, err := r.Write(chunk); err != nil {
+			t.Fatal("Write failed: ", err)
+		}
+	}
+	if len(r.buf) > maxRewriterBuffer {
+		t.Errorf("Buffered %d bytes, want at most %d", len(r.buf), maxRewriterBuffer)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal rewritten line: ", err)
+	}
+	if msg, _ := entry["message"].(string); len(msg) <= maxRewriterBuffer {
+		t.Errorf("Got message of %d bytes, want more than %d", len(msg), maxRewriterBuffer)
+	}
+}
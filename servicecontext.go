@@ -0,0 +1,44 @@
+package appengine
+
+import (
+	"os"
+	"sync"
+)
+
+// ServiceContext identifies the service and its version reported errors
+// belong to, so Error Reporting can group them accordingly. See
+// https://cloud.google.com/error-reporting/reference/rest/v1beta1/ServiceContext
+type ServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+var (
+	serviceContextOnce sync.Once
+	serviceContext     *ServiceContext
+
+	// serviceContextDetector is used by Formatter when ServiceContext is
+	// not set. Replaced in tests.
+	serviceContextDetector = DetectServiceContext
+)
+
+// DetectServiceContext returns ServiceContext populated from the environment
+// variables set by App Engine (GAE_SERVICE, GAE_VERSION) or Cloud Run
+// (K_SERVICE, K_REVISION). The result is cached for the lifetime of the
+// process. Returns nil if service name cannot be determined.
+func DetectServiceContext() *ServiceContext {
+	serviceContextOnce.Do(func() {
+		serviceContext = detectServiceContext()
+	})
+	return serviceContext
+}
+
+func detectServiceContext() *ServiceContext {
+	if s := os.Getenv("GAE_SERVICE"); s != "" {
+		return &ServiceContext{Service: s, Version: os.Getenv("GAE_VERSION")}
+	}
+	if s := os.Getenv("K_SERVICE"); s != "" {
+		return &ServiceContext{Service: s, Version: os.Getenv("K_REVISION")}
+	}
+	return nil
+}
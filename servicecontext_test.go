@@ -0,0 +1,49 @@
+package appengine
+
+import (
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestDetectServiceContext(t *testing.T) {
+	for _, v := range []string{"GAE_SERVICE", "GAE_VERSION", "K_SERVICE", "K_REVISION"} {
+		defer setenv(v, "")()
+	}
+	if sc := detectServiceContext(); sc != nil {
+		t.Errorf("detectServiceContext() = %+v, want nil", sc)
+	}
+
+	defer setenv("K_SERVICE", "run-service")()
+	defer setenv("K_REVISION", "run-service-00001")()
+	want := &ServiceContext{Service: "run-service", Version: "run-service-00001"}
+	if sc := detectServiceContext(); !reflect.DeepEqual(sc, want) {
+		t.Errorf("detectServiceContext() = %+v, want %+v", sc, want)
+	}
+
+	defer setenv("GAE_SERVICE", "default")()
+	defer setenv("GAE_VERSION", "20190101t000000")()
+	want = &ServiceContext{Service: "default", Version: "20190101t000000"}
+	if sc := detectServiceContext(); !reflect.DeepEqual(sc, want) {
+		t.Errorf("detectServiceContext() = %+v, want %+v", sc, want)
+	}
+}
+
+func TestServiceContext(t *testing.T) {
+	formatter := &Formatter{ServiceContext: &ServiceContext{Service: "api", Version: "v1"}}
+
+	e := log.WithField("a", 1)
+	e.Level = log.ErrorLevel
+	entry := formatToMap(t, formatter, e)
+	want := map[string]interface{}{"service": "api", "version": "v1"}
+	if !reflect.DeepEqual(entry["serviceContext"], want) {
+		t.Errorf("serviceContext = %v, want %v", entry["serviceContext"], want)
+	}
+
+	e.Level = log.InfoLevel
+	entry = formatToMap(t, formatter, e)
+	if _, set := entry["serviceContext"]; set {
+		t.Errorf("serviceContext set on INFO entry: %v", entry["serviceContext"])
+	}
+}
@@ -0,0 +1,68 @@
+package appengine
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Severity is a log entry severity as understood by Cloud Logging. See
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logseverity
+type Severity string
+
+// Severities recognized by Cloud Logging, from the least to the most severe.
+const (
+	SeverityDefault   Severity = "DEFAULT"
+	SeverityDebug     Severity = "DEBUG"
+	SeverityInfo      Severity = "INFO"
+	SeverityNotice    Severity = "NOTICE"
+	SeverityWarning   Severity = "WARNING"
+	SeverityError     Severity = "ERROR"
+	SeverityCritical  Severity = "CRITICAL"
+	SeverityAlert     Severity = "ALERT"
+	SeverityEmergency Severity = "EMERGENCY"
+)
+
+var severityRank = map[Severity]int{
+	SeverityDefault:   0,
+	SeverityDebug:     100,
+	SeverityInfo:      200,
+	SeverityNotice:    300,
+	SeverityWarning:   400,
+	SeverityError:     500,
+	SeverityCritical:  600,
+	SeverityAlert:     700,
+	SeverityEmergency: 800,
+}
+
+// rank returns numeric value of the severity as defined by Cloud Logging.
+// Unknown values are ranked the same as DEFAULT.
+func (s Severity) rank() int {
+	return severityRank[s]
+}
+
+// severityFromName returns the severity with the given case-insensitive
+// name, or an error if Cloud Logging doesn't recognize it.
+func severityFromName(name string) (Severity, error) {
+	s := Severity(strings.ToUpper(name))
+	if _, ok := severityRank[s]; !ok {
+		return "", fmt.Errorf("unknown severity %q", name)
+	}
+	return s, nil
+}
+
+// severityOverride is the type of the value stored by WithSeverity.
+type severityOverride Severity
+
+// WithSeverity returns a new entry that is emitted with the given severity,
+// regardless of its level. Useful for severities that have no matching
+// logrus level, like CRITICAL without terminating the program, or NOTICE.
+func WithSeverity(entry *log.Entry, s Severity) *log.Entry {
+	return entry.WithField("severity", severityOverride(s))
+}
+
+func isSeverityOverride(v interface{}) bool {
+	_, ok := v.(severityOverride)
+	return ok
+}
@@ -0,0 +1,48 @@
+package appengine
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestWithSeverity(t *testing.T) {
+	formatter := &Formatter{}
+
+	e := WithSeverity(log.WithField("a", 1), SeverityNotice)
+	e.Level = log.InfoLevel
+	entry := formatToMap(t, formatter, e)
+
+	if entry["severity"] != "NOTICE" {
+		t.Errorf("severity = %v, want NOTICE", entry["severity"])
+	}
+	if entry["level"] != "info" {
+		t.Errorf("level = %v, want info", entry["level"])
+	}
+	if _, set := entry["fields.severity"]; set {
+		t.Errorf("Override emitted as a field: %v", entry["fields.severity"])
+	}
+}
+
+func TestSeverityMap(t *testing.T) {
+	formatter := &Formatter{SeverityMap: map[log.Level]Severity{
+		log.TraceLevel: SeverityDefault,
+		log.WarnLevel:  SeverityNotice,
+	}}
+	for _, tc := range []struct {
+		level log.Level
+		want  string
+	}{
+		{log.TraceLevel, "DEFAULT"},
+		{log.WarnLevel, "NOTICE"},
+		{log.DebugLevel, "DEBUG"},
+		{log.ErrorLevel, "ERROR"},
+	} {
+		e := log.WithField("a", 1)
+		e.Level = tc.level
+		entry := formatToMap(t, formatter, e)
+		if entry["severity"] != tc.want {
+			t.Errorf("severity of %s entry = %v, want %s", tc.level, entry["severity"], tc.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package appengine
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// signatureKey is the key of the field the entry signature is stored under.
+const signatureKey = "signature"
+
+// Signer signs canonicalized entries, making entries archived outside of
+// Cloud Logging tamper-evident. Implementations backed by Cloud KMS or other
+// key management services can be plugged in via Formatter.Signer.
+type Signer interface {
+	Sign(payload []byte) ([]byte, error)
+}
+
+type hmacSigner []byte
+
+// HMACSigner returns a Signer computing HMAC-SHA256 with the given key.
+func HMACSigner(key []byte) Signer {
+	return hmacSigner(key)
+}
+
+func (s hmacSigner) Sign(payload []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+// VerifyHMAC reports whether the formatted entry carries a valid signature
+// produced by HMACSigner with the given key.
+func VerifyHMAC(entry []byte, key []byte) (bool, error) {
+	var data map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(entry))
+	d.UseNumber()
+	if err := d.Decode(&data); err != nil {
+		return false, err
+	}
+	encoded, ok := data[signatureKey].(string)
+	if !ok {
+		return false, errors.New("entry has no signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false, err
+	}
+	delete(data, signatureKey)
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+	want, _ := hmacSigner(key).Sign(payload)
+	return hmac.Equal(sig, want), nil
+}
+
+// signature returns base64-encoded signature of the canonical form of data:
+// compact JSON with keys of all objects sorted.
+func (f *Formatter) signature(data log.Fields) (string, error) {
+	b := &bytes.Buffer{}
+	if err := encodeObject(b, data, nil, false, nil); err != nil {
+		return "", err
+	}
+	// Round trip through generic values, so that nested structs are
+	// canonicalized the same way a verifier decoding the entry sees them.
+	var v interface{}
+	d := json.NewDecoder(b)
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sig, err := f.Signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,78 @@
+package appengine
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type failingSigner struct{}
+
+func (failingSigner) Sign([]byte) ([]byte, error) { return nil, errors.New("KMS is down") }
+
+func TestSigner(t *testing.T) {
+	key := []byte("secret")
+	formatter := &Formatter{Signer: HMACSigner(key), FieldOrder: HighSignalFieldOrder}
+	req := NewHTTPRequest(httptest.NewRequest("GET", "/audit?q=<x>", nil))
+	e := log.WithFields(log.Fields{
+		"user":         "walrus",
+		"n":            12345678901,
+		httpRequestKey: req,
+	})
+	e.Message = "Access granted"
+
+	b, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+	entry := formatToMap(t, formatter, e)
+	if _, ok := entry[signatureKey].(string); !ok {
+		t.Fatalf("%s = %v, want a string", signatureKey, entry[signatureKey])
+	}
+
+	if ok, err := VerifyHMAC(b, key); !ok || err != nil {
+		t.Errorf("VerifyHMAC() = %v, %v, want true, nil", ok, err)
+	}
+	if ok, _ := VerifyHMAC(b, []byte("other")); ok {
+		t.Error("Signature verified with a different key")
+	}
+	tampered := bytes.Replace(b, []byte("walrus"), []byte("gopher"), 1)
+	if ok, _ := VerifyHMAC(tampered, key); ok {
+		t.Error("Signature verified for a tampered entry")
+	}
+
+	if _, err := (&Formatter{Signer: failingSigner{}}).Format(e); err == nil {
+		t.Error("Format did not return an error when signing failed")
+	}
+}
+
+func TestSignerFieldClash(t *testing.T) {
+	key := []byte("secret")
+	e := log.WithField(signatureKey, "x")
+
+	for _, policy := range []ClashPolicy{ClashRename, ClashOverride} {
+		formatter := &Formatter{Signer: HMACSigner(key), ClashPolicy: policy}
+		b, err := formatter.Format(e)
+		if err != nil {
+			t.Fatal("Unable to format entry: ", err)
+		}
+		entry := formatToMap(t, formatter, e)
+		if entry["fields."+signatureKey] != "x" {
+			t.Errorf("policy %d: fields.%s = %v, want x", policy, signatureKey, entry["fields."+signatureKey])
+		}
+		if ok, err := VerifyHMAC(b, key); !ok || err != nil {
+			t.Errorf("policy %d: VerifyHMAC() = %v, %v, want true, nil", policy, ok, err)
+		}
+	}
+
+	if _, err := (&Formatter{Signer: HMACSigner(key), ClashPolicy: ClashError}).Format(e); err == nil {
+		t.Error("ClashError: Format succeeded, want error")
+	}
+	entry := formatToMap(t, &Formatter{}, e)
+	if entry[signatureKey] != "x" {
+		t.Errorf("%s = %v without Signer, want x", signatureKey, entry[signatureKey])
+	}
+}
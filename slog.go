@@ -0,0 +1,254 @@
+//go:build go1.21
+
+package appengine
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SlogHandler is a slog.Handler that produces the same output as Formatter,
+// so services migrating from logrus to log/slog keep the same log pipeline.
+// Attribute groups are rendered as nested objects. Context passed to the
+// logging methods is handled the same way as logrus.Entry.Context.
+type SlogHandler struct {
+	formatter *Formatter
+	logger    *log.Logger
+	opts      slog.HandlerOptions
+	mu        *sync.Mutex
+	out       io.Writer
+
+	// attrs are attributes added with WithAttrs, already placed into their
+	// groups. groups is the current group path.
+	attrs  log.Fields
+	groups []string
+}
+
+// NewSlogHandler returns a handler writing entries formatted by f to w. If
+// opts is nil, the default options are used. opts.ReplaceAttr is called
+// for every non-group attribute, and for the built-in time, level and
+// message attributes. Replaced values of the built-in attributes are used,
+// but their output keys are decided by f (see Formatter.FieldMap), and they
+// can't be dropped. The source attribute is not passed to ReplaceAttr.
+func NewSlogHandler(w io.Writer, f *Formatter, opts *slog.HandlerOptions) *SlogHandler {
+	h := &SlogHandler{
+		formatter: f,
+		logger:    log.New(),
+		mu:        &sync.Mutex{},
+		out:       w,
+		attrs:     log.Fields{},
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.logger.ReportCaller = h.opts.AddSource
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *SlogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return l >= min
+}
+
+// Handle implements slog.Handler.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	data := cloneNested(h.attrs)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	addAttrs(data, h.groups, attrs, h.opts.ReplaceAttr)
+
+	t, l, msg := r.Time, r.Level, r.Message
+	if replace := h.opts.ReplaceAttr; replace != nil {
+		if a := replace(nil, slog.Time(slog.TimeKey, t)); a.Key != "" && a.Value.Kind() == slog.KindTime {
+			t = a.Value.Time()
+		}
+		if a := replace(nil, slog.Any(slog.LevelKey, l)); a.Key != "" {
+			if v, ok := a.Value.Resolve().Any().(slog.Level); ok {
+				l = v
+			}
+		}
+		if a := replace(nil, slog.String(slog.MessageKey, msg)); a.Key != "" {
+			msg = a.Value.Resolve().String()
+		}
+	}
+
+	level, severity := slogLevel(l)
+	if severity != "" {
+		// The override is recognized by its type, so it's stored under a
+		// key not used by the attributes, and an attribute named severity
+		// goes through the clash handling of the Formatter.
+		key := "severity"
+		for _, taken := data[key]; taken; _, taken = data[key] {
+			key = "_" + key
+		}
+		data[key] = severityOverride(severity)
+	}
+	if t.IsZero() {
+		key := "timestamp"
+		for _, taken := data[key]; taken; _, taken = data[key] {
+			key = "_" + key
+		}
+		data[key] = noTimestamp{}
+	}
+	entry := &log.Entry{
+		Logger:  h.logger,
+		Data:    data,
+		Time:    t,
+		Level:   level,
+		Message: msg,
+		Context: ctx,
+	}
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		entry.Caller = &frame
+	}
+
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.out.Write(b)
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = cloneNested(h.attrs)
+	addAttrs(c.attrs, h.groups, attrs, h.opts.ReplaceAttr)
+	return &c
+}
+
+// WithGroup implements slog.Handler.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &c
+}
+
+// slogLevel maps slog level to the closest logrus level. Levels above
+// Error, which logrus can only express with Fatal and Panic, are mapped to
+// Error level with CRITICAL severity.
+func slogLevel(l slog.Level) (log.Level, Severity) {
+	switch {
+	case l < slog.LevelInfo:
+		return log.DebugLevel, ""
+	case l < slog.LevelWarn:
+		return log.InfoLevel, ""
+	case l < slog.LevelError:
+		return log.WarnLevel, ""
+	case l < slog.LevelError+4:
+		return log.ErrorLevel, ""
+	default:
+		return log.ErrorLevel, SeverityCritical
+	}
+}
+
+// addAttrs adds the attributes to fields, in the nested map of the group
+// path. Groups are only created if there's anything to put into them.
+func addAttrs(fields log.Fields, groups []string, attrs []slog.Attr, replace func([]string, slog.Attr) slog.Attr) {
+	added := log.Fields{}
+	for _, a := range attrs {
+		addAttr(added, a, groups, replace)
+	}
+	if len(added) > 0 {
+		mergeNested(groupFields(fields, groups), added)
+	}
+}
+
+// mergeNested adds fields from src to dst, merging nested groups present
+// in both.
+func mergeNested(dst, src log.Fields) {
+	for k, v := range src {
+		if sub, ok := v.(log.Fields); ok {
+			if existing, ok := dst[k].(log.Fields); ok {
+				mergeNested(existing, sub)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// addAttr adds the attribute to fields, following slog.Handler rules:
+// replace, if set, is called for non-group attributes with the path of
+// groups they're in, attributes with empty keys are ignored, groups with
+// empty keys are inlined, and empty groups are omitted. Errors inside groups
+// are replaced by their messages, since Formatter only serializes top-level
+// errors.
+func addAttr(fields log.Fields, a slog.Attr, groups []string, replace func([]string, slog.Attr) slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup && replace != nil {
+		a = replace(groups, slog.Attr{Key: a.Key, Value: v})
+		v = a.Value.Resolve()
+	}
+	if v.Kind() == slog.KindGroup {
+		attrs := v.Group()
+		if a.Key == "" {
+			for _, a := range attrs {
+				addAttr(fields, a, groups, replace)
+			}
+			return
+		}
+		sub := log.Fields{}
+		groups = append(groups[:len(groups):len(groups)], a.Key)
+		for _, a := range attrs {
+			addAttr(sub, a, groups, replace)
+		}
+		if len(sub) > 0 {
+			mergeNested(fields, log.Fields{a.Key: sub})
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	value := v.Any()
+	if err, ok := value.(error); ok && len(groups) > 0 {
+		value = err.Error()
+	}
+	fields[a.Key] = value
+}
+
+// groupFields returns the nested map for the group path, creating it if
+// necessary.
+func groupFields(fields log.Fields, groups []string) log.Fields {
+	for _, g := range groups {
+		sub, ok := fields[g].(log.Fields)
+		if !ok {
+			sub = log.Fields{}
+			fields[g] = sub
+		}
+		fields = sub
+	}
+	return fields
+}
+
+// cloneNested returns a deep copy of fields with nested groups.
+func cloneNested(fields log.Fields) log.Fields {
+	c := make(log.Fields, len(fields))
+	for k, v := range fields {
+		if sub, ok := v.(log.Fields); ok {
+			v = cloneNested(sub)
+		}
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,160 @@
+//go:build go1.21
+
+package appengine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSlogHandlerMatchesFormatter(t *testing.T) {
+	formatter := &Formatter{TraceKey: "trace"}
+	ts := time.Unix(1500000000, 123)
+
+	b := &bytes.Buffer{}
+	logger := slog.New(NewSlogHandler(b, formatter, nil))
+	record := slog.NewRecord(ts, slog.LevelWarn, "hello", 0)
+	record.AddAttrs(slog.String("trace", "projects/p/traces/abc"), slog.Int("n", 1))
+	if err := logger.Handler().Handle(context.Background(), record); err != nil {
+		t.Fatal("Handle failed: ", err)
+	}
+
+	e := log.WithFields(log.Fields{"trace": "projects/p/traces/abc", "n": 1})
+	e.Time = ts
+	e.Level = log.WarnLevel
+	e.Message = "hello"
+	want, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+
+	if b.String() != string(want) {
+		t.Errorf("Got %s, want %s", b, want)
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	b := &bytes.Buffer{}
+	h := NewSlogHandler(b, &Formatter{}, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug})
+	logger := slog.New(h).With("service", "api").WithGroup("req").With("id", 7)
+
+	logger.Log(context.Background(), slog.LevelError+4, "boom",
+		slog.Group("user", slog.String("name", "walrus")),
+		slog.Any("error", errors.New("wild walrus")))
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if entry["severity"] != "CRITICAL" {
+		t.Errorf("severity = %v, want CRITICAL", entry["severity"])
+	}
+	want := map[string]interface{}{
+		"id":    float64(7),
+		"user":  map[string]interface{}{"name": "walrus"},
+		"error": "wild walrus",
+	}
+	if !reflect.DeepEqual(entry["req"], want) {
+		t.Errorf("req = %v, want %v", entry["req"], want)
+	}
+	if entry["service"] != "api" {
+		t.Errorf("service = %v, want api", entry["service"])
+	}
+	l, _ := entry[sourceLocationKey].(map[string]interface{})
+	if name, _ := l["function"].(string); !strings.HasSuffix(name, ".TestSlogHandler") {
+		t.Errorf("%s = %v, want location of the Log call", sourceLocationKey, entry[sourceLocationKey])
+	}
+
+	if h.Enabled(context.Background(), slog.LevelDebug-1) {
+		t.Error("Level below the minimum is enabled")
+	}
+}
+
+func TestSlogHandlerReplaceAttr(t *testing.T) {
+	b := &bytes.Buffer{}
+	var paths []string
+	h := NewSlogHandler(b, &Formatter{}, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			paths = append(paths, strings.Join(append(groups, a.Key), "."))
+			switch a.Key {
+			case "password":
+				return slog.String(a.Key, "[REDACTED]")
+			case "drop":
+				return slog.Attr{}
+			case slog.MessageKey:
+				return slog.String(a.Key, "replaced "+a.Value.String())
+			case slog.TimeKey:
+				return slog.Time(a.Key, time.Unix(1500000000, 0))
+			}
+			return a
+		},
+	})
+	slog.New(h).WithGroup("req").With("password", "hunter2").Info("hello", "drop", 1, "id", 7)
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if want := map[string]interface{}{"password": "[REDACTED]", "id": float64(7)}; !reflect.DeepEqual(entry["req"], want) {
+		t.Errorf("req = %v, want %v", entry["req"], want)
+	}
+	if entry["message"] != "replaced hello" {
+		t.Errorf("message = %v, want the replaced one", entry["message"])
+	}
+	if ts, _ := entry["timestamp"].(map[string]interface{}); ts["seconds"] != float64(1500000000) {
+		t.Errorf("timestamp = %v, want the replaced one", entry["timestamp"])
+	}
+	want := []string{"req.password", "req.drop", "req.id", "time", "level", "msg"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("ReplaceAttr called for %v, want %v", paths, want)
+	}
+}
+
+func TestSlogHandlerSeverityAttr(t *testing.T) {
+	b := &bytes.Buffer{}
+	slog.New(NewSlogHandler(b, &Formatter{}, nil)).Log(context.Background(), slog.LevelError+4, "boom", "severity", "user")
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if entry["severity"] != "CRITICAL" || entry["fields.severity"] != "user" {
+		t.Errorf("severity = %v, fields.severity = %v, want CRITICAL and user", entry["severity"], entry["fields.severity"])
+	}
+}
+
+func TestSlogHandlerConformance(t *testing.T) {
+	b := &bytes.Buffer{}
+	formatter := &Formatter{
+		TimestampFormat: TimestampFormatRFC3339,
+		FieldMap:        FieldMap{FieldKeyMessage: slog.MessageKey},
+	}
+	results := func() []map[string]any {
+		var ms []map[string]any
+		d := json.NewDecoder(bytes.NewReader(b.Bytes()))
+		for d.More() {
+			m := map[string]any{}
+			if err := d.Decode(&m); err != nil {
+				t.Fatal("Unable to unmarshal formatted entry: ", err)
+			}
+			// Fields added by the formatter, not by the handler.
+			delete(m, "severity")
+			delete(m, "logging.googleapis.com/trace_sampled")
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	if err := slogtest.TestHandler(NewSlogHandler(b, formatter, nil), results); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,141 @@
+package appengine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var thisPackage = reflect.TypeOf((*Formatter)(nil)).Elem().PkgPath()
+
+// funcPackage returns package path of the fully qualified function name.
+func funcPackage(name string) string {
+	lastSlash := strings.LastIndexByte(name, '/')
+	if i := strings.IndexByte(name[lastSlash+1:], '.'); i >= 0 {
+		return name[:lastSlash+1+i]
+	}
+	return name
+}
+
+// stackTrace returns entry message followed by the stack trace, formatted
+// like runtime.Stack output.
+func stackTrace(entry *log.Entry) string {
+	err, _ := entry.Data[log.ErrorKey].(error)
+	frames := errorStack(err)
+	if frames == nil {
+		frames = entryCallSite(entry)
+	}
+	if frames == nil {
+		frames = callerStack()
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString(entry.Message)
+	if err != nil {
+		if entry.Message != "" {
+			b.WriteString(": ")
+		}
+		b.WriteString(err.Error())
+	}
+	b.WriteString("\n\n")
+	b.WriteString(goroutineHeader())
+	for _, frame := range frames {
+		fmt.Fprintf(b, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+	return b.String()
+}
+
+// errorStack returns the stack trace of the innermost error in the chain that
+// carries one, or nil if there's none. Both StackFramer and
+// github.com/pkg/errors style StackTrace methods are recognized.
+func errorStack(err error) []runtime.Frame {
+	var stack []runtime.Frame
+	for ; err != nil; err = errors.Unwrap(err) {
+		var frames []runtime.Frame
+		if s, ok := err.(StackFramer); ok {
+			frames = s.StackFrames()
+		} else {
+			frames = pkgErrorsStack(err)
+		}
+		if len(frames) > 0 {
+			stack = frames
+		}
+	}
+	return stack
+}
+
+// pkgErrorsStack returns frames of the stack trace returned by
+// StackTrace method of errors created by github.com/pkg/errors. The method
+// returns a slice of program counters of a package-specific type, so it's
+// matched using reflection to avoid depending on the package.
+func pkgErrorsStack(err error) []runtime.Frame {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() {
+		return nil
+	}
+	t := m.Type()
+	if t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Slice || t.Out(0).Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	trace := m.Call(nil)[0]
+	pcs := make([]uintptr, trace.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(trace.Index(i).Uint())
+	}
+	return framesFromPCs(pcs)
+}
+
+// framesFromPCs converts program counters, as returned by runtime.Callers,
+// into frames.
+func framesFromPCs(pcs []uintptr) []runtime.Frame {
+	if len(pcs) == 0 {
+		return nil
+	}
+	var stack []runtime.Frame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame)
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// callerStack returns the stack of the current goroutine, excluding frames
+// of logrus, this package and its subpackages, such as errorreporting hook
+// formatting the entry.
+func callerStack() []runtime.Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		pkg := funcPackage(frame.Function)
+		if pkg != thisPackage && !strings.HasPrefix(pkg, thisPackage+"/") && pkg != "github.com/sirupsen/logrus" {
+			stack = append(stack, frame)
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// goroutineHeader returns the first line of runtime.Stack output, which
+// identifies the current goroutine.
+func goroutineHeader() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		return string(buf[:i+1])
+	}
+	return "goroutine 1 [running]:\n"
+}
@@ -0,0 +1,114 @@
+package appengine
+
+import (
+	"errors"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestFuncPackage(t *testing.T) {
+	tests := map[string]string{
+		"github.com/sirupsen/logrus.(*Entry).Log":        "github.com/sirupsen/logrus",
+		"github.com/gelraen/appengine-formatter.newUUID": "github.com/gelraen/appengine-formatter",
+		"main.main":                      "main",
+		"example.com/a.b/c.(*T).M.func1": "example.com/a.b/c",
+	}
+	for name, want := range tests {
+		if got := funcPackage(name); got != want {
+			t.Errorf("funcPackage(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if thisPackage != "github.com/gelraen/appengine-formatter" {
+		t.Errorf("thisPackage = %q", thisPackage)
+	}
+}
+
+func TestStackTraceCaptured(t *testing.T) {
+	formatter := &Formatter{StackTrace: true}
+
+	e := log.WithField("a", 1)
+	e.Level = log.ErrorLevel
+	e.Message = "oops"
+	entry := formatToMap(t, formatter, e)
+
+	stack, _ := entry["stack_trace"].(string)
+	if !regexp.MustCompile(`^oops\n\ngoroutine \d+ \[running\]:\n`).MatchString(stack) {
+		t.Errorf("Unexpected stack_trace header: %q", stack)
+	}
+	// Test functions are in this package too, so only testing frames are left.
+	if strings.Contains(stack, "appengine-formatter.") || !strings.Contains(stack, "testing.tRunner") {
+		t.Errorf("Unexpected frames in stack_trace: %q", stack)
+	}
+}
+
+func TestStackTraceFromError(t *testing.T) {
+	formatter := &Formatter{StackTrace: true}
+
+	err := &stackError{errors.New("wild walrus"), []runtime.Frame{
+		{Function: "db.Query", File: "/src/db/db.go", Line: 42},
+		{Function: "main.main", File: "/src/main.go", Line: 7},
+	}}
+	e := log.WithError(err)
+	e.Level = log.ErrorLevel
+	e.Message = "oops"
+	entry := formatToMap(t, formatter, e)
+
+	want := "oops: wild walrus\n\ngoroutine \\d+ \\[running\\]:\n" +
+		"db\\.Query\\(\\.\\.\\.\\)\n\t/src/db/db\\.go:42\n" +
+		"main\\.main\\(\\.\\.\\.\\)\n\t/src/main\\.go:7\n$"
+	if stack, _ := entry["stack_trace"].(string); !regexp.MustCompile(want).MatchString(stack) {
+		t.Errorf("stack_trace = %q, want %q", stack, want)
+	}
+
+	e.Level = log.InfoLevel
+	entry = formatToMap(t, formatter, e)
+	if _, set := entry["stack_trace"]; set {
+		t.Errorf("stack_trace set on INFO entry: %v", entry["stack_trace"])
+	}
+}
+
+// pkgErrorsError mimics errors created by github.com/pkg/errors.
+type pkgErrorsError struct {
+	error
+	stack []pkgErrorsFrame
+}
+
+type pkgErrorsFrame uintptr
+
+type pkgErrorsStackTrace []pkgErrorsFrame
+
+func (e *pkgErrorsError) StackTrace() pkgErrorsStackTrace { return e.stack }
+
+func newPkgErrorsError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	stack := make([]pkgErrorsFrame, n)
+	for i, pc := range pcs[:n] {
+		stack[i] = pkgErrorsFrame(pc)
+	}
+	return &pkgErrorsError{errors.New(msg), stack}
+}
+
+func TestPkgErrorsStackTrace(t *testing.T) {
+	log.SetReportCaller(true)
+	defer log.SetReportCaller(false)
+	formatter := &Formatter{StackTrace: true, ErrorReporting: true}
+
+	e := log.WithError(newPkgErrorsError("wild walrus"))
+	e.Level = log.ErrorLevel
+	e.Caller = &runtime.Frame{Function: "main.handler", File: "/src/main.go", Line: 10}
+	entry := formatToMap(t, formatter, e)
+
+	stack, _ := entry["stack_trace"].(string)
+	if !strings.Contains(stack, "TestPkgErrorsStackTrace(...)\n\t") {
+		t.Errorf("stack_trace doesn't contain frames of the error: %q", stack)
+	}
+	l, _ := entry["context"].(map[string]interface{})["reportLocation"].(map[string]interface{})
+	if name, _ := l["functionName"].(string); !strings.HasSuffix(name, ".TestPkgErrorsStackTrace") {
+		t.Errorf("reportLocation = %v, want the place the error was created at", l)
+	}
+}
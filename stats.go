@@ -0,0 +1,73 @@
+package appengine
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// statsKey is the key of the field window statistics are emitted under.
+	statsKey = "stats"
+	// statsWindow is the length of the window statistics are collected
+	// over, in seconds.
+	statsWindow = 60
+)
+
+// WindowStats are the numbers of entries formatted by a Formatter during the
+// last minute, including the entry they are attached to.
+type WindowStats struct {
+	WindowSeconds int `json:"windowSeconds"`
+	Entries       int `json:"entries"`
+	Errors        int `json:"errors"`
+}
+
+type statsBucket struct {
+	second  int64
+	entries int
+	errors  int
+}
+
+// windowStats counts entries in per-second buckets.
+type windowStats struct {
+	mu      sync.Mutex
+	buckets [statsWindow]statsBucket
+}
+
+// add counts an entry logged at t, and returns statistics for the window
+// ending at t.
+func (s *windowStats) add(t time.Time, isError bool) WindowStats {
+	sec := t.Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := &s.buckets[(sec%statsWindow+statsWindow)%statsWindow]
+	if b.second != sec {
+		*b = statsBucket{second: sec}
+	}
+	b.entries++
+	if isError {
+		b.errors++
+	}
+
+	r := WindowStats{WindowSeconds: statsWindow}
+	for _, b := range s.buckets {
+		if b.second > sec-statsWindow && b.second <= sec {
+			r.Entries += b.entries
+			r.Errors += b.errors
+		}
+	}
+	return r
+}
+
+// windowStats counts the entry and returns the value of "stats" field for
+// it, or nil if it should be omitted.
+func (f *Formatter) windowStats(t time.Time, severity Severity) *WindowStats {
+	if !f.WindowStats {
+		return nil
+	}
+	isError := severity.rank() >= SeverityError.rank()
+	s := f.stats.add(t, isError)
+	if !isError {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,45 @@
+package appengine
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestWindowStats(t *testing.T) {
+	formatter := &Formatter{WindowStats: true}
+	start := time.Unix(1500000000, 0)
+
+	for _, test := range []struct {
+		after time.Duration
+		level log.Level
+		want  interface{}
+	}{
+		{0, log.InfoLevel, nil},
+		{time.Second, log.ErrorLevel, map[string]interface{}{"windowSeconds": 60.0, "entries": 2.0, "errors": 1.0}},
+		{30 * time.Second, log.InfoLevel, nil},
+		{59 * time.Second, log.ErrorLevel, map[string]interface{}{"windowSeconds": 60.0, "entries": 4.0, "errors": 2.0}},
+		// The first entry is out of the window now.
+		{60 * time.Second, log.ErrorLevel, map[string]interface{}{"windowSeconds": 60.0, "entries": 4.0, "errors": 3.0}},
+		{5 * time.Minute, log.ErrorLevel, map[string]interface{}{"windowSeconds": 60.0, "entries": 1.0, "errors": 1.0}},
+	} {
+		e := log.WithTime(start.Add(test.after))
+		e.Level = test.level
+		entry := formatToMap(t, formatter, e)
+		got, ok := entry[statsKey].(map[string]interface{})
+		if test.want == nil {
+			if ok {
+				t.Errorf("%s: %s = %v, want none", test.after, statsKey, got)
+			}
+			continue
+		}
+		want := test.want.(map[string]interface{})
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %v, want %v", test.after, statsKey, got, want)
+				break
+			}
+		}
+	}
+}
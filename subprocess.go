@@ -0,0 +1,110 @@
+package appengine
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// componentLabel is the label ForwardLogs puts subprocess name under.
+const componentLabel = "component"
+
+// maxForwardedLine limits the length of lines ForwardLogs forwards, longer
+// ones are truncated.
+const maxForwardedLine = 1 << 20
+
+// textLevelRe matches a level word at the beginning of a plain text line,
+// like "ERROR ...", "[warn] ..." or "info: ...".
+var textLevelRe = regexp.MustCompile(`^\s*[\[(<]?([A-Za-z]+)[\])>]?(?:[:\s]|$)`)
+
+// ForwardLogs reads log lines written by a subprocess from r, e.g. its
+// stdout pipe, and logs them with logger, so they end up in the parent's
+// log pipeline labeled with "component" set to name. JSON lines are parsed
+// the same way Rewriter does: message, level, timestamp and caller keys are
+// recognized, and the rest become entry fields. Severity of plain text lines
+// is guessed from a level word at the beginning of the line, such as "ERROR"
+// or "[warn]", and is INFO otherwise. Lines longer than 1 MiB are truncated,
+// and the rest is discarded, so r is always drained and the subprocess never
+// blocks on writing. ForwardLogs returns when r reaches EOF, and may be
+// called concurrently for multiple subprocesses.
+func ForwardLogs(logger *log.Logger, name string, r io.Reader) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := readLine(br, maxForwardedLine)
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			entry, severity, msg := forwardedEntry(logger, line)
+			entry = WithLabels(entry, map[string]string{componentLabel: name})
+			entry.Log(severityLevel(severity), msg)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readLine reads a line from r without the line terminator. At most max
+// bytes of the line are returned, the rest is read and discarded.
+func readLine(r *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if n := max - len(line); n > 0 {
+			if len(chunk) > n {
+				chunk = chunk[:n]
+			}
+			line = append(line, chunk...)
+		}
+		if err != nil || !isPrefix {
+			return line, err
+		}
+	}
+}
+
+// forwardedEntry returns the entry, severity and message for a line of
+// subprocess output.
+func forwardedEntry(logger *log.Logger, line []byte) (*log.Entry, Severity, string) {
+	data, ts := rewriteLine(line)
+	msg, _ := data["message"].(string)
+	delete(data, "message")
+	severity, structured := data["severity"].(Severity)
+	if structured {
+		delete(data, "severity")
+	} else {
+		severity = SeverityInfo
+		if m := textLevelRe.FindStringSubmatch(msg); m != nil {
+			if s, ok := severityNames[strings.ToLower(m[1])]; ok {
+				severity = s
+			}
+		}
+	}
+	entry := log.NewEntry(logger)
+	if !ts.IsZero() {
+		delete(data, "timestamp")
+		entry = entry.WithTime(ts)
+	}
+	entry = WithSeverity(entry.WithFields(data), severity)
+	return entry, severity, msg
+}
+
+// severityLevel returns logrus level to log an entry of the given severity
+// with. Fatal and Panic levels are never returned, since logging with them
+// terminates the program.
+func severityLevel(s Severity) log.Level {
+	switch {
+	case s.rank() >= SeverityError.rank():
+		return log.ErrorLevel
+	case s.rank() >= SeverityWarning.rank():
+		return log.WarnLevel
+	case s == SeverityDebug:
+		return log.DebugLevel
+	default:
+		return log.InfoLevel
+	}
+}
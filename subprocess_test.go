@@ -0,0 +1,119 @@
+package appengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestForwardLogs(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	logger.Formatter = &Formatter{}
+	logger.Level = log.DebugLevel
+
+	input := `{"level":"error","ts":1500000000,"msg":"structured","job":7}
+[WARN] plain warning
+notice: disk is almost full
+
+just text
+{"msg":"debug: no level key"}
+{"level":"debug","severity":"error","msg":"both keys"}
+`
+	if err := ForwardLogs(logger, "worker", strings.NewReader(input)); err != nil {
+		t.Fatal("ForwardLogs failed: ", err)
+	}
+
+	want := []struct {
+		message  string
+		severity string
+	}{
+		{"structured", "ERROR"},
+		{"[WARN] plain warning", "WARNING"},
+		{"notice: disk is almost full", "NOTICE"},
+		{"just text", "INFO"},
+		{"debug: no level key", "DEBUG"},
+		{"both keys", "ERROR"},
+	}
+	d := json.NewDecoder(b)
+	for i := 0; d.More(); i++ {
+		entry := make(map[string]interface{})
+		if err := d.Decode(&entry); err != nil {
+			t.Fatal("Unable to unmarshal formatted entry: ", err)
+		}
+		if i >= len(want) {
+			t.Fatalf("Got unexpected entry %v", entry)
+		}
+		if entry["message"] != want[i].message || entry["severity"] != want[i].severity {
+			t.Errorf("Entry #%d: got message %v and severity %v, want %q and %q", i, entry["message"], entry["severity"], want[i].message, want[i].severity)
+		}
+		if labels, _ := entry[labelsKey].(map[string]interface{}); labels[componentLabel] != "worker" {
+			t.Errorf("Entry #%d: %s = %v, want component label", i, labelsKey, entry[labelsKey])
+		}
+		if i == 0 {
+			if ts, _ := entry["timestamp"].(map[string]interface{}); ts["seconds"] != float64(1500000000) {
+				t.Errorf("timestamp = %v, want the one from the line", entry["timestamp"])
+			}
+			if entry["job"] != float64(7) {
+				t.Errorf("job = %v, want 7", entry["job"])
+			}
+		}
+	}
+}
+
+func TestForwardLogsLongLine(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	logger.Formatter = &Formatter{}
+
+	input := strings.Repeat("x", maxForwardedLine+100) + "\nnext line\n"
+	if err := ForwardLogs(logger, "worker", strings.NewReader(input)); err != nil {
+		t.Fatal("ForwardLogs failed: ", err)
+	}
+	d := json.NewDecoder(b)
+	var messages []string
+	for d.More() {
+		entry := make(map[string]interface{})
+		if err := d.Decode(&entry); err != nil {
+			t.Fatal("Unable to unmarshal formatted entry: ", err)
+		}
+		msg, _ := entry["message"].(string)
+		messages = append(messages, msg)
+	}
+	if len(messages) != 2 || len(messages[0]) != maxForwardedLine || messages[1] != "next line" {
+		t.Errorf("Got %d entries, want the truncated line followed by the next one", len(messages))
+	}
+}
+
+func TestForwardLogsTimestampObject(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = b
+	logger.Formatter = &Formatter{}
+
+	input := `{"msg":"object","timestamp":{"seconds":1,"nanos":2}}
+{"msg":"empty","timestamp":{}}
+`
+	if err := ForwardLogs(logger, "worker", strings.NewReader(input)); err != nil {
+		t.Fatal("ForwardLogs failed: ", err)
+	}
+	d := json.NewDecoder(b)
+	for _, want := range []map[string]interface{}{
+		{"seconds": float64(1), "nanos": float64(2)},
+		{},
+	} {
+		entry := make(map[string]interface{})
+		if err := d.Decode(&entry); err != nil {
+			t.Fatal("Unable to unmarshal formatted entry: ", err)
+		}
+		if !reflect.DeepEqual(entry["fields.timestamp"], want) {
+			t.Errorf("%v: fields.timestamp = %v, want %v", entry["message"], entry["fields.timestamp"], want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package appengine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SummaryFormatter returns a Formatter configured so that entries render
+// well in the summary line of Logs Explorer: every entry has a non-empty
+// message and a recognized severity, trace, span and sampling decision
+// found under "trace", "spanId" and "traceSampled" fields are emitted under
+// the special keys, and traces are qualified with projectID (detected from
+// the environment if empty), so they link to Cloud Trace.
+func SummaryFormatter(projectID string) *Formatter {
+	return &Formatter{
+		ProjectID:          projectID,
+		TraceKey:           "trace",
+		SpanIDKey:          "spanId",
+		TraceSampledKey:    "traceSampled",
+		FieldOrder:         HighSignalFieldOrder,
+		SummaryConformance: true,
+	}
+}
+
+// normalizeSeverity returns one of the severities recognized by Cloud
+// Logging, matching s case-insensitively, or DEFAULT.
+func normalizeSeverity(s Severity) Severity {
+	if _, ok := severityRank[s]; ok {
+		return s
+	}
+	if n := Severity(strings.ToUpper(string(s))); n.rank() > 0 {
+		return n
+	}
+	if n, ok := severityNames[strings.ToLower(string(s))]; ok {
+		return n
+	}
+	return SeverityDefault
+}
+
+// summaryMessage returns the message to show in the summary line of an
+// entry with an empty message: message of the attached error, the entry
+// fields as key=value pairs, or the severity, whichever is available.
+func (f *Formatter) summaryMessage(entry *log.Entry, severity Severity) string {
+	if err, ok := entry.Data[log.ErrorKey].(error); ok {
+		return err.Error()
+	}
+	keys := make([]string, 0, len(entry.Data))
+	for k, v := range entry.Data {
+		if !f.isSpecialField(entry, k, v) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return string(severity)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, f.redactField(k, entry.Data[k]))
+	}
+	return strings.Join(pairs, " ")
+}
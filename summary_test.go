@@ -0,0 +1,91 @@
+package appengine
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// summaryFixture describes an entry and how it must render in the summary
+// line of Logs Explorer.
+type summaryFixture struct {
+	Name     string
+	Level    string
+	Message  string
+	Error    string
+	Severity Severity
+	Fields   log.Fields
+	Want     struct {
+		Message  string
+		Severity string
+		Trace    string
+	}
+}
+
+var summaryTraceRe = regexp.MustCompile(`^projects/[^/]+/traces/[0-9a-f]{32}$`)
+
+// checkSummaryRules verifies the rules Logs Explorer relies on to render the
+// summary line of an entry.
+func checkSummaryRules(t *testing.T, name string, entry map[string]interface{}) {
+	t.Helper()
+	if msg, ok := entry["message"].(string); !ok || msg == "" {
+		t.Errorf("%s: message = %#v, want non-empty string, otherwise JSON payload is shown", name, entry["message"])
+	}
+	if s, _ := entry["severity"].(string); Severity(s).rank() == 0 && s != string(SeverityDefault) {
+		t.Errorf("%s: severity = %#v, want one of the Cloud Logging severities", name, entry["severity"])
+	}
+	if trace, ok := entry[traceKey]; ok {
+		if s, _ := trace.(string); !summaryTraceRe.MatchString(s) {
+			t.Errorf("%s: %s = %#v, want trace resource name", name, traceKey, trace)
+		}
+	}
+	if sampled, ok := entry[traceSampledKey]; ok {
+		if _, ok := sampled.(bool); !ok {
+			t.Errorf("%s: %s = %#v, want bool", name, traceSampledKey, sampled)
+		}
+	}
+}
+
+func TestSummaryConformance(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/summary.json")
+	if err != nil {
+		t.Fatal("Unable to read fixtures: ", err)
+	}
+	var fixtures []summaryFixture
+	if err := json.Unmarshal(b, &fixtures); err != nil {
+		t.Fatal("Unable to parse fixtures: ", err)
+	}
+
+	formatter := SummaryFormatter("my-project")
+	for _, fx := range fixtures {
+		e := log.WithFields(fx.Fields)
+		if fx.Error != "" {
+			e = e.WithError(errors.New(fx.Error))
+		}
+		if fx.Severity != "" {
+			e = WithSeverity(e, fx.Severity)
+		}
+		level, err := log.ParseLevel(fx.Level)
+		if err != nil {
+			t.Fatalf("%s: %v", fx.Name, err)
+		}
+		e.Level = level
+		e.Message = fx.Message
+
+		entry := formatToMap(t, formatter, e)
+		checkSummaryRules(t, fx.Name, entry)
+		if entry["message"] != fx.Want.Message {
+			t.Errorf("%s: message = %#v, want %q", fx.Name, entry["message"], fx.Want.Message)
+		}
+		if entry["severity"] != fx.Want.Severity {
+			t.Errorf("%s: severity = %#v, want %q", fx.Name, entry["severity"], fx.Want.Severity)
+		}
+		if trace, _ := entry[traceKey].(string); trace != fx.Want.Trace {
+			t.Errorf("%s: %s = %q, want %q", fx.Name, traceKey, trace, fx.Want.Trace)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package appengine
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TimestampFormat selects how Formatter emits entry timestamps.
+type TimestampFormat int
+
+// Supported timestamp formats.
+const (
+	// TimestampFormatObject emits "timestamp" object with "seconds" and
+	// "nanos" since Unix epoch.
+	TimestampFormatObject TimestampFormat = iota
+	// TimestampFormatRFC3339 emits "time" string in RFC3339 format with
+	// nanoseconds, also accepted by the logging agent.
+	TimestampFormatRFC3339
+	// TimestampFormatSplit emits top-level "timestampSeconds" and
+	// "timestampNanos" keys, the alternate form recognized by the logging
+	// agent, for fluentd configurations that only accept it.
+	TimestampFormatSplit
+)
+
+// noTimestamp is stored in entry data to emit an entry with zero time
+// without timestamp, e.g. for slog records, which have zero time if it's
+// unknown.
+type noTimestamp struct{}
+
+func isNoTimestamp(v interface{}) bool {
+	_, ok := v.(noTimestamp)
+	return ok
+}
+
+// hasTimestamp reports whether the timestamp of the entry is emitted.
+func (f *Formatter) hasTimestamp(entry *log.Entry) bool {
+	if f.DisableTimestamp {
+		return false
+	}
+	if entry.Time.IsZero() {
+		_, omitted := typedFieldKey(entry, "", isNoTimestamp)
+		return !omitted
+	}
+	return true
+}
+
+// addTimestamp adds the timestamp of the entry to data in the configured
+// format.
+func (f *Formatter) addTimestamp(data log.Fields, ts time.Time) {
+	if f.TimestampPrecision > 0 {
+		ts = ts.Truncate(f.TimestampPrecision)
+	}
+	switch f.TimestampFormat {
+	case TimestampFormatRFC3339:
+		data[f.FieldMap.resolve(FieldKeyTime)] = ts.UTC().Format(time.RFC3339Nano)
+	case TimestampFormatSplit:
+		data[f.FieldMap.resolve(FieldKeyTimestampSeconds)] = ts.Unix()
+		data[f.FieldMap.resolve(FieldKeyTimestampNanos)] = ts.Nanosecond()
+	default:
+		data[f.FieldMap.resolve(FieldKeyTimestamp)] = map[string]interface{}{
+			"seconds": ts.Unix(),
+			"nanos":   ts.Nanosecond(),
+		}
+	}
+}
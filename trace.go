@@ -0,0 +1,164 @@
+package appengine
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Special keys recognized by the logging agent, see
+// https://cloud.google.com/logging/docs/agent/configuration#special-fields
+const (
+	traceKey  = "logging.googleapis.com/trace"
+	spanIDKey = "logging.googleapis.com/spanId"
+
+	traceSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+// addTraceFields moves trace-related entry fields into the corresponding
+// special keys.
+func (f *Formatter) addTraceFields(data log.Fields, entry *log.Entry) {
+	var tc TraceContext
+	if v, ok := findTypedField(entry, traceContextKey, isTraceContext); ok {
+		tc, _ = traceContextValue(v)
+	} else if f.TraceExtractor != nil && entry.Context != nil {
+		tc, _ = f.TraceExtractor(entry.Context)
+	}
+	if tc.TraceID != "" {
+		data[traceSampledKey] = tc.Sampled
+	}
+	trace, span := tc.TraceID, tc.SpanID
+	if v, ok := traceIDField(entry, f.TraceKey); ok {
+		trace = v
+	}
+	if v, ok := traceIDField(entry, f.SpanIDKey); ok {
+		span = v
+	}
+	if trace != "" {
+		r := f.resolveTrace(trace, entry)
+		data[traceKey] = r.name
+		if f.TraceURL && r.url != "" {
+			data["trace_url"] = r.url
+		}
+	}
+	if span != "" {
+		data[spanIDKey] = span
+	}
+	if v, ok := lookupField(entry, f.TraceSampledKey); ok {
+		switch v := v.(type) {
+		case bool:
+			data[traceSampledKey] = v
+		case string:
+			if sampled, err := strconv.ParseBool(v); err == nil {
+				data[traceSampledKey] = sampled
+			}
+		}
+	}
+}
+
+// traceIDField returns the value of the trace or span ID field of the entry
+// as a string. Nil and empty values are ignored.
+func traceIDField(entry *log.Entry, k string) (string, bool) {
+	v, ok := lookupField(entry, k)
+	if !ok || v == nil {
+		return "", false
+	}
+	s := fmt.Sprint(v)
+	return s, s != ""
+}
+
+// resolvedTrace holds values derived from a trace ID.
+type resolvedTrace struct {
+	// name is the trace resource name, or raw trace ID if project is
+	// unknown.
+	name string
+	// url is a link to the trace in Cloud Console, empty if project is
+	// unknown or the link wasn't needed.
+	url string
+}
+
+// traceCacheKey identifies the inputs of resolveTrace that vary between
+// entries of the same request.
+type traceCacheKey struct {
+	f     *Formatter
+	trace string
+	// project is the value of TraceProjectIDKey field of the entry.
+	project string
+}
+
+// resolveTrace turns raw trace ID into a trace resource name, if project ID
+// is known. The result is cached in the request cache of the entry context,
+// if there's one, so that project resolution is done once per request.
+func (f *Formatter) resolveTrace(trace string, entry *log.Entry) resolvedTrace {
+	cache := requestCacheFrom(entry.Context)
+	var key traceCacheKey
+	if cache != nil {
+		key = traceCacheKey{f: f, trace: trace}
+		if v, ok := lookupField(entry, f.TraceProjectIDKey); ok {
+			key.project = fmt.Sprint(v)
+		}
+		if r, ok := cache.trace(key); ok {
+			return r
+		}
+	}
+	r := resolvedTrace{name: trace}
+	if !strings.HasPrefix(trace, "projects/") {
+		project := f.traceProject(entry)
+		if project == "" {
+			// Not cached, project ID may still be detected in the
+			// background.
+			return r
+		}
+		r.name = "projects/" + project + "/traces/" + trace
+	}
+	if f.TraceURL {
+		r.url = traceURL(r.name)
+	}
+	cache.putTrace(key, r)
+	return r
+}
+
+// traceProject returns project ID to qualify trace ID of the entry with.
+func (f *Formatter) traceProject(entry *log.Entry) string {
+	if v, ok := lookupField(entry, f.TraceProjectIDKey); ok {
+		if p := fmt.Sprint(v); p != "" {
+			return p
+		}
+	}
+	if f.TraceProjectID != "" {
+		return f.TraceProjectID
+	}
+	if f.ProjectID != "" {
+		return f.ProjectID
+	}
+	if f.NonBlockingDetection {
+		return f.backgroundProjectID()
+	}
+	project, _ := detectedProjectID()
+	return project
+}
+
+// splitTrace splits trace resource name into project ID and trace ID.
+func splitTrace(trace string) (project string, id string, ok bool) {
+	parts := strings.Split(trace, "/")
+	if len(parts) != 4 || parts[0] != "projects" || parts[2] != "traces" || parts[1] == "" || parts[3] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[3], true
+}
+
+// traceURL returns a link to the trace in Cloud Console, or an empty string
+// if trace is not a fully qualified resource name.
+func traceURL(trace string) string {
+	project, id, ok := splitTrace(trace)
+	if !ok {
+		return ""
+	}
+	return "https://console.cloud.google.com/traces/list?" + url.Values{
+		"project": {project},
+		"tid":     {id},
+	}.Encode()
+}
@@ -0,0 +1,152 @@
+package appengine
+
+import (
+	"encoding/json"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func formatToMap(t *testing.T, formatter *Formatter, e *log.Entry) map[string]interface{} {
+	t.Helper()
+	b, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+
+	entry := make(map[string]interface{})
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	return entry
+}
+
+func TestTraceKey(t *testing.T) {
+	formatter := &Formatter{TraceKey: "trace"}
+
+	entry := formatToMap(t, formatter, log.WithField("trace", "projects/p/traces/abc"))
+
+	if entry[traceKey] != "projects/p/traces/abc" {
+		t.Errorf("%s not set, got %v", traceKey, entry[traceKey])
+	}
+	if _, set := entry["trace"]; set {
+		t.Error("trace field is not removed")
+	}
+}
+
+func TestTraceKeyClash(t *testing.T) {
+	formatter := &Formatter{TraceKey: "trace"}
+
+	entry := formatToMap(t, formatter, log.WithFields(log.Fields{
+		"trace":  "projects/p/traces/abc",
+		traceKey: "something else",
+	}))
+
+	if entry[traceKey] != "projects/p/traces/abc" {
+		t.Errorf("%s not set, got %v", traceKey, entry[traceKey])
+	}
+	if entry["fields."+traceKey] != "something else" {
+		t.Errorf("fields.%s not set, got %v", traceKey, entry["fields."+traceKey])
+	}
+}
+
+func TestSpanIDKey(t *testing.T) {
+	formatter := &Formatter{SpanIDKey: "span"}
+
+	entry := formatToMap(t, formatter, log.WithField("span", "000000000000004a"))
+
+	if entry[spanIDKey] != "000000000000004a" {
+		t.Errorf("%s not set, got %v", spanIDKey, entry[spanIDKey])
+	}
+	if _, set := entry["span"]; set {
+		t.Error("span field is not removed")
+	}
+}
+
+func TestEmptyTraceAndSpanID(t *testing.T) {
+	formatter := &Formatter{TraceKey: "trace", SpanIDKey: "span", ProjectID: "p"}
+
+	for _, v := range []interface{}{nil, ""} {
+		entry := formatToMap(t, formatter, log.WithFields(log.Fields{"trace": v, "span": v}))
+
+		for _, k := range []string{traceKey, spanIDKey} {
+			if _, set := entry[k]; set {
+				t.Errorf("%s is set to %v for %#v ID", k, entry[k], v)
+			}
+		}
+	}
+}
+
+func TestTraceSampledKey(t *testing.T) {
+	formatter := &Formatter{TraceSampledKey: "sampled"}
+
+	for _, v := range []interface{}{true, "true", "1"} {
+		entry := formatToMap(t, formatter, log.WithField("sampled", v))
+		if entry[traceSampledKey] != true {
+			t.Errorf("%s not set for %#v, got %v", traceSampledKey, v, entry[traceSampledKey])
+		}
+		if _, set := entry["sampled"]; set {
+			t.Error("sampled field is not removed")
+		}
+	}
+
+	entry := formatToMap(t, formatter, log.WithField("sampled", "maybe"))
+	if _, set := entry[traceSampledKey]; set {
+		t.Errorf("%s set for invalid value, got %v", traceSampledKey, entry[traceSampledKey])
+	}
+}
+
+func TestProjectID(t *testing.T) {
+	formatter := &Formatter{TraceKey: "trace", ProjectID: "my-project"}
+
+	entry := formatToMap(t, formatter, log.WithField("trace", "abc"))
+	if entry[traceKey] != "projects/my-project/traces/abc" {
+		t.Errorf("%s not qualified, got %v", traceKey, entry[traceKey])
+	}
+
+	entry = formatToMap(t, formatter, log.WithField("trace", "projects/other/traces/abc"))
+	if entry[traceKey] != "projects/other/traces/abc" {
+		t.Errorf("%s changed, got %v", traceKey, entry[traceKey])
+	}
+}
+
+func TestTraceURL(t *testing.T) {
+	formatter := &Formatter{TraceKey: "trace", ProjectID: "my-project", TraceURL: true}
+
+	entry := formatToMap(t, formatter, log.WithField("trace", "abc"))
+	if want := "https://console.cloud.google.com/traces/list?project=my-project&tid=abc"; entry["trace_url"] != want {
+		t.Errorf("trace_url = %v, want %s", entry["trace_url"], want)
+	}
+
+	formatter.ProjectID = ""
+	entry = formatToMap(t, formatter, log.WithField("trace", "abc"))
+	if _, set := entry["trace_url"]; set {
+		t.Errorf("trace_url set without project, got %v", entry["trace_url"])
+	}
+}
+
+func TestTraceProjectID(t *testing.T) {
+	formatter := &Formatter{
+		TraceKey:          "trace",
+		ProjectID:         "runtime-project",
+		TraceProjectID:    "trace-project",
+		TraceProjectIDKey: "trace_project",
+	}
+
+	entry := formatToMap(t, formatter, log.WithField("trace", "abc"))
+	if entry[traceKey] != "projects/trace-project/traces/abc" {
+		t.Errorf("%s not qualified with TraceProjectID, got %v", traceKey, entry[traceKey])
+	}
+
+	entry = formatToMap(t, formatter, log.WithFields(log.Fields{
+		"trace":         "abc",
+		"trace_project": "entry-project",
+	}))
+	if entry[traceKey] != "projects/entry-project/traces/abc" {
+		t.Errorf("%s not qualified with per-entry project, got %v", traceKey, entry[traceKey])
+	}
+	if _, set := entry["trace_project"]; set {
+		t.Error("trace_project field is not removed")
+	}
+}
@@ -0,0 +1,126 @@
+package appengine
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const traceContextKey = "traceContext"
+
+// TraceContext identifies the trace and span an entry belongs to. If a value
+// of this type, or a pointer to it, is attached to the entry under any key,
+// its fields are emitted under the trace special keys. Fields named by
+// TraceKey, SpanIDKey and TraceSampledKey take precedence over it.
+type TraceContext struct {
+	// TraceID is a 32 hex digits trace ID, or a fully qualified trace
+	// resource name.
+	TraceID string
+	// SpanID is a 16 hex digits span ID.
+	SpanID  string
+	Sampled bool
+}
+
+func isTraceContext(v interface{}) bool {
+	switch v.(type) {
+	case TraceContext, *TraceContext:
+		return true
+	}
+	return false
+}
+
+// traceContextValue returns the TraceContext stored in a field value.
+func traceContextValue(v interface{}) (TraceContext, bool) {
+	switch v := v.(type) {
+	case TraceContext:
+		return v, true
+	case *TraceContext:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return TraceContext{}, false
+}
+
+// WithTraceContext returns a copy of ctx carrying the trace context, which
+// is added to every entry formatted with that context, see PushField.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return PushField(ctx, traceContextKey, tc)
+}
+
+// TraceContextFrom returns the trace context stored in ctx by
+// WithTraceContext.
+func TraceContextFrom(ctx context.Context) (TraceContext, bool) {
+	return traceContextValue(ContextFields(ctx)[traceContextKey])
+}
+
+// traceContextFromRequest extracts trace context from X-Cloud-Trace-Context
+// header of the request, or from W3C traceparent header if the former is
+// missing or malformed.
+func traceContextFromRequest(r *http.Request) (TraceContext, bool) {
+	if tc, ok := ParseCloudTraceContext(r.Header.Get("X-Cloud-Trace-Context")); ok {
+		return tc, true
+	}
+	return ParseTraceparent(r.Header.Get("traceparent"))
+}
+
+// ParseCloudTraceContext parses X-Cloud-Trace-Context header value of the
+// form "TRACE_ID/SPAN_ID;o=OPTIONS", set by Google Cloud load balancers and
+// App Engine. Span ID is converted from decimal to hex, as expected by Cloud
+// Logging, and is left empty if missing or malformed. It returns false if
+// trace ID is malformed.
+func ParseCloudTraceContext(h string) (TraceContext, bool) {
+	h, opts := splitOnce(h, ";")
+	trace, span := splitOnce(h, "/")
+	trace = strings.ToLower(trace)
+	if len(trace) != 32 || !isHex(trace) {
+		return TraceContext{}, false
+	}
+	tc := TraceContext{TraceID: trace, Sampled: opts == "o=1"}
+	if id, err := strconv.ParseUint(span, 10, 64); err == nil && id != 0 {
+		tc.SpanID = strconv.FormatUint(id, 16)
+		tc.SpanID = strings.Repeat("0", 16-len(tc.SpanID)) + tc.SpanID
+	}
+	return tc, true
+}
+
+// ParseTraceparent parses W3C traceparent header value of the form
+// "VERSION-TRACE_ID-SPAN_ID-FLAGS", for services behind proxies that only
+// propagate W3C headers. It returns false if the value is malformed. See
+// https://www.w3.org/TR/trace-context/#traceparent-header
+func ParseTraceparent(h string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, false
+	}
+	version, trace, span, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isHex(version) || version == "ff" || version == "00" && len(parts) != 4 {
+		return TraceContext{}, false
+	}
+	if len(trace) != 32 || !isHex(trace) || trace == strings.Repeat("0", 32) ||
+		len(span) != 16 || !isHex(span) || span == strings.Repeat("0", 16) ||
+		len(flags) != 2 || !isHex(flags) {
+		return TraceContext{}, false
+	}
+	f, _ := strconv.ParseUint(flags, 16, 8)
+	return TraceContext{TraceID: trace, SpanID: span, Sampled: f&1 == 1}, true
+}
+
+// isHex reports whether s is not empty and consists of lower case hex
+// digits only.
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func splitOnce(s, sep string) (string, string) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):]
+	}
+	return s, ""
+}
@@ -0,0 +1,103 @@
+package appengine
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func TestParseCloudTraceContext(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		want   TraceContext
+		ok     bool
+	}{
+		{testTraceID + "/1;o=1", TraceContext{testTraceID, "0000000000000001", true}, true},
+		{testTraceID + "/18446744073709551615;o=0", TraceContext{testTraceID, "ffffffffffffffff", false}, true},
+		{testTraceID + "/255", TraceContext{testTraceID, "00000000000000ff", false}, true},
+		{testTraceID, TraceContext{TraceID: testTraceID}, true},
+		{"4BF92F3577B34DA6A3CE929D0E0E4736/x", TraceContext{TraceID: testTraceID}, true},
+		{"", TraceContext{}, false},
+		{"abc/1;o=1", TraceContext{}, false},
+	} {
+		got, ok := ParseCloudTraceContext(test.header)
+		if got != test.want || ok != test.ok {
+			t.Errorf("ParseCloudTraceContext(%q) = %+v, %v, want %+v, %v", test.header, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		want   TraceContext
+		ok     bool
+	}{
+		{"00-" + testTraceID + "-00f067aa0ba902b7-01", TraceContext{testTraceID, "00f067aa0ba902b7", true}, true},
+		{"00-" + testTraceID + "-00f067aa0ba902b7-00", TraceContext{testTraceID, "00f067aa0ba902b7", false}, true},
+		{"01-" + testTraceID + "-00f067aa0ba902b7-03-future", TraceContext{testTraceID, "00f067aa0ba902b7", true}, true},
+		{"00-" + testTraceID + "-00f067aa0ba902b7-01-extra", TraceContext{}, false},
+		{"ff-" + testTraceID + "-00f067aa0ba902b7-01", TraceContext{}, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", TraceContext{}, false},
+		{"00-" + testTraceID + "-0000000000000000-01", TraceContext{}, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", TraceContext{}, false},
+		{"", TraceContext{}, false},
+	} {
+		got, ok := ParseTraceparent(test.header)
+		if got != test.want || ok != test.ok {
+			t.Errorf("ParseTraceparent(%q) = %+v, %v, want %+v, %v", test.header, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestTraceContextField(t *testing.T) {
+	formatter := &Formatter{ProjectID: "p", SpanIDKey: "span"}
+	ctx := WithTraceContext(context.Background(), TraceContext{testTraceID, "00f067aa0ba902b7", true})
+
+	entry := formatToMap(t, formatter, log.WithContext(ctx).WithField("span", "0000000000000001"))
+	if entry[traceKey] != "projects/p/traces/"+testTraceID {
+		t.Errorf("%s = %v, want projects/p/traces/%s", traceKey, entry[traceKey], testTraceID)
+	}
+	if entry[spanIDKey] != "0000000000000001" {
+		t.Errorf("%s = %v, want span ID from the keyed field", spanIDKey, entry[spanIDKey])
+	}
+	if entry[traceSampledKey] != true {
+		t.Errorf("%s = %v, want true", traceSampledKey, entry[traceSampledKey])
+	}
+	if _, ok := entry[traceContextKey]; ok {
+		t.Errorf("%s emitted as is", traceContextKey)
+	}
+}
+
+type spanKey struct{}
+
+func TestTraceExtractor(t *testing.T) {
+	formatter := &Formatter{
+		ProjectID: "p",
+		TraceExtractor: func(ctx context.Context) (TraceContext, bool) {
+			tc, ok := ctx.Value(spanKey{}).(TraceContext)
+			return tc, ok
+		},
+	}
+	ctx := context.WithValue(context.Background(), spanKey{}, TraceContext{testTraceID, "00f067aa0ba902b7", false})
+
+	entry := formatToMap(t, formatter, log.WithContext(ctx))
+	if entry[traceKey] != "projects/p/traces/"+testTraceID || entry[spanIDKey] != "00f067aa0ba902b7" || entry[traceSampledKey] != false {
+		t.Errorf("Got %s %v, %s %v, %s %v", traceKey, entry[traceKey], spanIDKey, entry[spanIDKey], traceSampledKey, entry[traceSampledKey])
+	}
+
+	// Trace context stored in the context explicitly takes precedence.
+	ctx = WithTraceContext(ctx, TraceContext{TraceID: "projects/q/traces/other"})
+	entry = formatToMap(t, formatter, log.WithContext(ctx))
+	if entry[traceKey] != "projects/q/traces/other" {
+		t.Errorf("%s = %v, want projects/q/traces/other", traceKey, entry[traceKey])
+	}
+
+	entry = formatToMap(t, formatter, log.WithField("x", 1))
+	if _, ok := entry[traceKey]; ok {
+		t.Errorf("%s set for entry without context", traceKey)
+	}
+}
@@ -0,0 +1,123 @@
+package appengine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Limits of Cloud Logging on entry labels.
+const (
+	maxLabelKeyLength   = 512
+	maxLabelValueLength = 64 * 1024
+)
+
+// Validate checks the configuration for mistakes that would otherwise
+// silently produce malformed entries, such as an input field key that
+// collides with an output key, labels Cloud Logging would reject, or
+// unrecognized severities. All found problems are reported in a single
+// error. If TraceURL is set without a project ID, Validate detects it like
+// Init does, unless NonBlockingDetection is set.
+func (f *Formatter) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	known := map[string]bool{}
+	output := map[string]string{}
+	for _, k := range fieldKeys {
+		known[k] = true
+		key := f.FieldMap.resolve(k)
+		if other, ok := output[key]; ok {
+			add("FieldMap maps both %q and %q to %q", other, k, key)
+		}
+		output[key] = k
+	}
+	for k := range f.FieldMap {
+		if !known[k] {
+			add("FieldMap key %q is not one of FieldKey* constants", k)
+		}
+	}
+
+	inputs := []struct{ name, key string }{
+		{"TraceKey", f.TraceKey},
+		{"SpanIDKey", f.SpanIDKey},
+		{"TraceSampledKey", f.TraceSampledKey},
+		{"TraceProjectIDKey", f.TraceProjectIDKey},
+		{"InsertIDKey", f.InsertIDKey},
+		{"LabelsKey", f.LabelsKey},
+	}
+	seen := map[string]string{}
+	for _, in := range inputs {
+		if in.key == "" {
+			continue
+		}
+		if other, ok := seen[in.key]; ok {
+			add("%s and %s are both %q", other, in.name, in.key)
+		}
+		seen[in.key] = in.name
+		if k, ok := output[in.key]; ok {
+			add("%s %q collides with the key of %q field", in.name, in.key, k)
+		}
+	}
+
+	keys := make([]string, 0, len(f.Labels))
+	for k := range f.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch {
+		case k == "":
+			add("Labels contain an empty key")
+		case len(k) > maxLabelKeyLength:
+			add("label key %.32q... is longer than %d bytes", k, maxLabelKeyLength)
+		}
+		if len(f.Labels[k]) > maxLabelValueLength {
+			add("value of label %q is longer than %d bytes", k, maxLabelValueLength)
+		}
+	}
+
+	for _, s := range []struct {
+		name  string
+		value Severity
+	}{
+		{"MinSeverity", f.MinSeverity},
+		{"ErrorReportingMinSeverity", f.ErrorReportingMinSeverity},
+	} {
+		if _, ok := severityRank[s.value]; s.value != "" && !ok {
+			add("%s %q is not a severity recognized by Cloud Logging", s.name, s.value)
+		}
+	}
+	for l, s := range f.SeverityMap {
+		if _, ok := severityRank[s]; !ok {
+			add("SeverityMap maps %s to %q, which is not a severity recognized by Cloud Logging", l, s)
+		}
+	}
+
+	if f.TimestampPrecision < 0 {
+		add("TimestampPrecision %v is negative", f.TimestampPrecision)
+	}
+	if f.TimestampFormat < TimestampFormatObject || f.TimestampFormat > TimestampFormatSplit {
+		add("unknown TimestampFormat %d", f.TimestampFormat)
+	}
+	if f.ClashPolicy < ClashRename || f.ClashPolicy > ClashError {
+		add("unknown ClashPolicy %d", f.ClashPolicy)
+	}
+	if f.KeyCasing < KeyCasingNone || f.KeyCasing > KeyCasingCamel {
+		add("unknown KeyCasing %d", f.KeyCasing)
+	}
+
+	if f.TraceURL && f.ProjectID == "" && f.TraceProjectID == "" && f.TraceProjectIDKey == "" && !f.NonBlockingDetection {
+		if _, err := detectedProjectID(); err != nil {
+			add("TraceURL is set, but project ID is not configured and can't be detected, %v", err)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("invalid formatter configuration: %s", strings.Join(problems, "; "))
+}
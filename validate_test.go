@@ -0,0 +1,53 @@
+package appengine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestValidate(t *testing.T) {
+	if err := (&Formatter{}).Validate(); err != nil {
+		t.Errorf("Validate() of zero Formatter = %v, want nil", err)
+	}
+	if err := SummaryFormatter("my-project").Validate(); err != nil {
+		t.Errorf("Validate() of SummaryFormatter = %v, want nil", err)
+	}
+
+	for _, test := range []struct {
+		desc string
+		f    *Formatter
+		want string
+	}{
+		{"trace key is message key", &Formatter{TraceKey: "message"}, `TraceKey "message" collides with the key of "message" field`},
+		{"trace key is renamed message key", &Formatter{TraceKey: "msg", FieldMap: FieldMap{FieldKeyMessage: "msg"}}, `TraceKey "msg" collides`},
+		{"same input keys", &Formatter{TraceKey: "t", SpanIDKey: "t"}, `TraceKey and SpanIDKey are both "t"`},
+		{"field map clash", &Formatter{FieldMap: FieldMap{FieldKeyLevel: "severity"}}, `FieldMap maps both "severity" and "level" to "severity"`},
+		{"unknown field map key", &Formatter{FieldMap: FieldMap{"msg": "message"}}, `FieldMap key "msg"`},
+		{"empty label key", &Formatter{Labels: map[string]string{"": "x"}}, "empty key"},
+		{"long label key", &Formatter{Labels: map[string]string{strings.Repeat("k", 513): "x"}}, "longer than 512 bytes"},
+		{"unknown min severity", &Formatter{MinSeverity: "WARN"}, `MinSeverity "WARN"`},
+		{"unknown mapped severity", &Formatter{SeverityMap: map[log.Level]Severity{log.WarnLevel: "warn"}}, `maps warning to "warn"`},
+		{"negative precision", &Formatter{TimestampPrecision: -1}, "negative"},
+		{"unknown clash policy", &Formatter{ClashPolicy: 42}, "unknown ClashPolicy"},
+	} {
+		err := test.f.Validate()
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: Validate() = %v, want error containing %q", test.desc, err, test.want)
+		}
+	}
+}
+
+func TestValidateTraceURLProject(t *testing.T) {
+	defer stubProjectIDDetector(func() (string, error) { return "", errors.New("not on GCP") })()
+
+	err := (&Formatter{TraceURL: true}).Validate()
+	if err == nil || !strings.Contains(err.Error(), "not on GCP") {
+		t.Errorf("Validate() = %v, want project ID error", err)
+	}
+	if err := (&Formatter{TraceURL: true, ProjectID: "my-project"}).Validate(); err != nil {
+		t.Errorf("Validate() with ProjectID = %v, want nil", err)
+	}
+}
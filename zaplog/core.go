@@ -0,0 +1,143 @@
+// Package zaplog provides a zapcore.Core rendering entries with the appengine
+// Formatter, so that codebases mixing zap and logrus produce the same
+// structured logs:
+//
+//	core := zaplog.NewCore(&appengine.Formatter{}, zapcore.Lock(os.Stdout), zap.InfoLevel)
+//	logger := zap.New(core, zap.AddCaller())
+//
+// Fields added with zap.Error keep the error value, so ErrorHint, ErrorKind,
+// ErrorChain and Error Reporting settings of the Formatter apply to them.
+package zaplog
+
+import (
+	"runtime"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap/zapcore"
+)
+
+type core struct {
+	zapcore.LevelEnabler
+	formatter *appengine.Formatter
+	out       zapcore.WriteSyncer
+	logger    *logrus.Logger
+	fields    logrus.Fields
+}
+
+// NewCore returns a core writing entries formatted by f to out.
+func NewCore(f *appengine.Formatter, out zapcore.WriteSyncer, enab zapcore.LevelEnabler) zapcore.Core {
+	logger := logrus.New()
+	logger.ReportCaller = true
+	return &core{
+		LevelEnabler: enab,
+		formatter:    f,
+		out:          out,
+		logger:       logger,
+		fields:       logrus.Fields{},
+	}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = addFields(c.fields, fields)
+	return &clone
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	level, severity := logrusLevel(ent.Level)
+	entry := &logrus.Entry{Logger: c.logger, Data: addFields(c.fields, fields)}
+	if severity != "" {
+		entry = appengine.WithSeverity(entry, severity)
+	}
+	entry.Time = ent.Time
+	entry.Level = level
+	entry.Message = ent.Message
+	if ent.Caller.Defined {
+		entry.Caller = &runtime.Frame{
+			PC:       ent.Caller.PC,
+			File:     ent.Caller.File,
+			Line:     ent.Caller.Line,
+			Function: ent.Caller.Function,
+		}
+	}
+
+	b, err := c.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := c.out.Write(b); err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// Same as zapcore.ioCore: make sure the entry is written before
+		// the process panics or exits.
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *core) Sync() error {
+	return c.out.Sync()
+}
+
+// logrusLevel maps zap level to logrus level. DPanic, which logrus has no
+// equivalent of, is mapped to Error level with CRITICAL severity.
+func logrusLevel(l zapcore.Level) (logrus.Level, appengine.Severity) {
+	switch l {
+	case zapcore.DebugLevel:
+		return logrus.DebugLevel, ""
+	case zapcore.InfoLevel:
+		return logrus.InfoLevel, ""
+	case zapcore.WarnLevel:
+		return logrus.WarnLevel, ""
+	case zapcore.ErrorLevel:
+		return logrus.ErrorLevel, ""
+	case zapcore.DPanicLevel:
+		return logrus.ErrorLevel, appengine.SeverityCritical
+	case zapcore.PanicLevel:
+		return logrus.PanicLevel, ""
+	case zapcore.FatalLevel:
+		return logrus.FatalLevel, ""
+	default:
+		if l < zapcore.DebugLevel {
+			return logrus.DebugLevel, ""
+		}
+		return logrus.FatalLevel, ""
+	}
+}
+
+// addFields returns a copy of data with fields added. Top-level error fields
+// keep the error value, everything else is encoded by
+// zapcore.MapObjectEncoder.
+func addFields(data logrus.Fields, fields []zapcore.Field) logrus.Fields {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range data {
+		enc.Fields[k] = v
+	}
+	errs := map[string]error{}
+	namespaced := false
+	for _, f := range fields {
+		f.AddTo(enc)
+		switch f.Type {
+		case zapcore.NamespaceType:
+			namespaced = true
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok && !namespaced {
+				errs[f.Key] = err
+			}
+		}
+	}
+	r := logrus.Fields(enc.Fields)
+	for k, err := range errs {
+		r[k] = err
+	}
+	return r
+}
@@ -0,0 +1,67 @@
+package zaplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	appengine "github.com/gelraen/appengine-formatter"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCoreMatchesFormatter(t *testing.T) {
+	formatter := &appengine.Formatter{TraceKey: "trace"}
+	ts := time.Unix(1500000000, 123)
+	err := errors.New("wild walrus")
+
+	b := &bytes.Buffer{}
+	c := NewCore(formatter, zapcore.AddSync(b), zapcore.DebugLevel).
+		With([]zapcore.Field{zap.String("trace", "projects/p/traces/abc")})
+	fields := []zapcore.Field{zap.Int("n", 1), zap.Error(err)}
+	if err := c.Write(zapcore.Entry{Level: zapcore.WarnLevel, Time: ts, Message: "hello"}, fields); err != nil {
+		t.Fatal("Write failed: ", err)
+	}
+
+	e := logrus.WithFields(logrus.Fields{"trace": "projects/p/traces/abc", "n": int64(1), "error": err})
+	e.Time = ts
+	e.Level = logrus.WarnLevel
+	e.Message = "hello"
+	want, err := formatter.Format(e)
+	if err != nil {
+		t.Fatal("Unable to format entry: ", err)
+	}
+
+	if b.String() != string(want) {
+		t.Errorf("Got %s, want %s", b, want)
+	}
+}
+
+func TestCore(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := zap.New(NewCore(&appengine.Formatter{}, zapcore.AddSync(b), zapcore.InfoLevel), zap.AddCaller())
+
+	logger.Debug("ignored")
+	logger.DPanic("boom", zap.Namespace("req"), zap.String("id", "7"))
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal("Unable to unmarshal formatted entry: ", err)
+	}
+	if entry["message"] != "boom" {
+		t.Errorf("message = %v, want boom", entry["message"])
+	}
+	if entry["severity"] != "CRITICAL" {
+		t.Errorf("severity = %v, want CRITICAL", entry["severity"])
+	}
+	if req, _ := entry["req"].(map[string]interface{}); req["id"] != "7" {
+		t.Errorf("req = %v, want map[id:7]", entry["req"])
+	}
+	l, _ := entry["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if l["function"] != "github.com/gelraen/appengine-formatter/zaplog.TestCore" {
+		t.Errorf("sourceLocation = %v, want location of the DPanic call", l)
+	}
+}